@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+/* Values of the AgeBucket column, in order */
+var ageBuckets = []string{"0-7", "8-30", "31-90", "90+"}
+
+/* ageNever is the AgeBucket of a device that has never completed a backup or never connected */
+const ageNever = "never"
+
+/* ageBucket returns the AgeBucket of a device last seen days ago */
+func ageBucket(days int) string {
+	switch {
+	case days <= 7:
+		return ageBuckets[0]
+	case days <= 30:
+		return ageBuckets[1]
+	case days <= 90:
+		return ageBuckets[2]
+	}
+	return ageBuckets[3]
+}
+
+// setAges fills AgeDays, AgeBucket and Stale for every device in the report. AgeDays counts the whole days since
+// the older of LastCompletedBackupDate and LastConnectedDate, so a device is stale, when staleDays is above zero,
+// if either date is more than staleDays ago. A device missing either date has no AgeDays, the AgeBucket "never",
+// and is stale. Users without devices, and the rows added by -usage rows, are left blank.
+func setAges(data ReportDataArray, now time.Time, staleDays int) {
+	for i := range data {
+		r := &data[i]
+		if r.DeviceUid == "" || r.usageOnly {
+			continue
+		}
+
+		days := -1 // Never
+		completed, errCompleted := parseReportTime(r.LastCompletedBackupDate)
+		connected, errConnected := parseReportTime(r.LastConnectedDate)
+		if errCompleted == nil && errConnected == nil {
+			oldest := completed
+			if connected.Before(oldest) {
+				oldest = connected
+			}
+			days = int(now.Sub(oldest).Hours() / 24)
+			if days < 0 {
+				days = 0 // Clock skew between this machine and the server
+			}
+		}
+
+		if days >= 0 {
+			r.AgeDays = strconv.Itoa(days)
+			r.AgeBucket = ageBucket(days)
+		} else {
+			r.AgeDays = ""
+			r.AgeBucket = ageNever
+		}
+		if staleDays > 0 {
+			r.Stale = "no"
+			if days < 0 || days > staleDays {
+				r.Stale = "yes"
+			}
+		}
+	}
+}
+
+// writeAgeSummary writes a table of the number of devices per org and AgeBucket, with the number of stale devices if
+// staleDays is above zero. A device with several rows in the report is counted once.
+func writeAgeSummary(w io.Writer, data ReportDataArray, staleDays int) {
+	type orgAges struct {
+		buckets map[string]int
+		stale   int
+		total   int
+	}
+	orgs := map[string]*orgAges{}
+	all := &orgAges{buckets: map[string]int{}}
+	counted := map[string]bool{}
+	for _, r := range data {
+		if r.DeviceUid == "" || r.usageOnly || counted[r.DeviceUid] {
+			continue
+		}
+		counted[r.DeviceUid] = true
+		if orgs[r.OrgName] == nil {
+			orgs[r.OrgName] = &orgAges{buckets: map[string]int{}}
+		}
+		for _, ages := range []*orgAges{orgs[r.OrgName], all} {
+			ages.buckets[r.AgeBucket]++
+			ages.total++
+			if r.Stale == "yes" {
+				ages.stale++
+			}
+		}
+	}
+
+	var names []string
+	for name := range orgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buckets := append(append([]string{}, ageBuckets...), ageNever)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Device age by org (days since the last completed backup or connection, whichever is older):")
+	fmt.Fprint(tw, "Org\t")
+	for _, bucket := range buckets {
+		fmt.Fprint(tw, bucket, "\t")
+	}
+	if staleDays > 0 {
+		fmt.Fprintf(tw, "Stale (>%d)\t", staleDays)
+	}
+	fmt.Fprintln(tw, "Devices\t")
+	row := func(name string, ages *orgAges) {
+		fmt.Fprint(tw, name, "\t")
+		for _, bucket := range buckets {
+			fmt.Fprint(tw, ages.buckets[bucket], "\t")
+		}
+		if staleDays > 0 {
+			fmt.Fprint(tw, ages.stale, "\t")
+		}
+		fmt.Fprint(tw, ages.total, "\t\n")
+	}
+	for _, name := range names {
+		row(name, orgs[name])
+	}
+	row("All orgs", all)
+	tw.Flush()
+}
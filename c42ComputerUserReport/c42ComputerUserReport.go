@@ -19,6 +19,27 @@ OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 SOFTWARE.
 
 Author: Todd Ojala
+Modified 10-17-2026
+	1. API calls moved to the shared code42/client package. One pooled connection is reused for every request.
+	2. HTTP status codes are checked, so a bad password is reported as an authentication failure.
+	3. API calls that fail with a dropped connection or a server error are retried with backoff (-retries, -retrywait).
+	   A device whose Computer data still can't be retrieved is reported with blank Computer fields.
+	4. Calls to the Computer resource are made by a pool of workers (-workers), capped at a number of API calls
+	   per second (-rps). The report order is unchanged.
+	5. Output formats: -format csv (default), json, ndjson, xlsx or html, written to the file given with -o
+	   (default output.<format>).
+	6. -columns chooses the columns and their order, with optional custom headers. New columns: Username, UserUid,
+	   DeviceGuid, OS, OSVersion and Version.
+	7. The report is now really sorted by last-connected date (see 05-17-2016), or by the columns given with -sort.
+	8. Users without devices are found with an indexed join on UserUid (client.Join) instead of a nested loop.
+	   Their rows now also have OrgName, read from the User resource (new OrgUid and OrgName of client.User).
+	9. The User resource is paged like DeviceBackupReport, instead of asking for 99999 users at once. A warning is
+	   printed if the number of users retrieved differs from the totalCount reported by the server.
+	10. The Computer fields come from the backup to the destination named in the row, not always the first one.
+	   -usage rows and -usage columns report the backup to every destination of a device.
+	11. -stale-days flags devices that haven't completed a backup or connected recently. New columns AgeDays,
+	   AgeBucket and Stale, and a summary of devices by org and age bucket at the end of the run.
+
 Last modified 05-25-2016
 	1. MIT License added to top comments section 
 	2. API version info added 
@@ -42,13 +63,14 @@ Last modified 05-25-2016
 	Added paging, because the DeviceBackupReport resource returns 1000 records max.
 	Paging still not added to Users query, because no limit is enforeed.
 
-The purpose of this script is to generate a customized report for Oliver Wyman, then write the results to a CSV
-file on disk, named output.csv .
+The purpose of this script is to generate a customized report for Oliver Wyman, then write the results to a file
+on disk: by default a CSV file named output.csv .
 
 Usage:
 
 Command to run
-	c42ComputerUserReport [-active] [-limit <number>]
+	c42ComputerUserReport [-active] [-limit <number>] [-workers <number>] [-rps <number>] [-retries <number>] [-retrywait <duration>]
+		[-columns <keys>] [-sort <keys>] [-usage match|rows|columns] [-stale-days <number>] [-format csv|json|ndjson|xlsx|html] [-o <path>]
 	Example command: c42ComputerUserReport -active -limit 100  (This example shows only active devices and limits
 	calls to the Computer API to 100)
 
@@ -64,6 +86,58 @@ Command Line Arguments
 
 	The optional command-line argument "-active" filters out all deactivated devices from the report. Only active devices appear.
 
+	The optional command-line argument "-workers" (default 4) sets how many calls to the Computer resource are made at the
+	same time, and "-rps" (default 10) caps the number of API calls made per second by all workers together, so the master
+	server is not overloaded. Use -rps 0 for no cap. The report is in the same order whatever the number of workers.
+
+	The optional command-line arguments "-retries" (default 3) and "-retrywait" (default 2s) control how API calls that fail with
+	a dropped connection, a server error or a rate limit are retried. The wait doubles after each attempt. Attempts are logged.
+
+	The optional command-line argument "-columns" chooses the columns of the report, in order, as a comma-separated list of
+	column keys. A key may be followed by =Label to give its column a different header. Keys:
+		email, username, devicename, devicestatus, selectedfiles, lastbackup, lastcompletedbackup, lastconnected,
+		bytestodo, filestodo, backupcompletepercentage, alerts, destination, orgname, os, osversion, version,
+		agedays, agebucket, stale, useruid, deviceuid
+	Without -columns, the report has the original 13 columns, email to orgname without username. selectedfiles,
+	lastbackup, bytestodo, filestodo, os, osversion and version come from the Computer resource, so they are blank for
+	devices past -limit. Example: -columns "email=User Email,devicename,deviceuid=Device GUID,os,version,lastconnected"
+
+	The optional command-line argument "-sort" sorts the report. It takes a comma-separated list of column keys (see
+	-columns; any column can be used, whether or not it is in the report), each optionally followed by :asc or :desc.
+	Later keys break ties in earlier ones. Dates are compared as points in time, numbers as numbers, and the rest as
+	text, ignoring case. Blank values come last in either direction, and users without devices always come after all
+	devices. The default is "lastconnected": devices that connected longest ago first. "-sort none" keeps the order
+	returned by the API. Example: -sort "orgname,lastconnected:desc"
+
+	The optional command-line argument "-usage" chooses how devices that back up to several destinations are reported.
+	The Computer resource has the backup of a device to each of its destinations (selectedfiles, lastbackup, bytestodo,
+	filestodo); they are matched to the Destination column by destination name.
+		match    One row per row of DeviceBackupReport, with the backup to its destination (the default). If the
+		         Computer resource has no backup to that destination, these fields are blank and the log says so.
+		rows     Also one row per device and destination for the destinations DeviceBackupReport doesn't list.
+		         DeviceStatus, LastCompletedBackup, LastConnected, BackupCompletePercentage, Alerts, Stale,
+		         AgeDays and AgeBucket are blank in these rows, and the age summary doesn't count them.
+		columns  One row per device, with a group of columns per destination: "<destination> SelectedFiles",
+		         "<destination> LastBackup", "<destination> BytesToDo" and "<destination> FilesToDo", after the
+		         columns chosen with -columns. -sort can't use these columns.
+
+	The optional command-line argument "-stale-days" flags stale devices: those whose LastCompletedBackup or
+	LastConnected date is more than N days ago, or missing. It adds three columns to the report, unless -columns
+	already has them: Stale (yes or no), AgeDays (whole days since the older of the two dates) and AgeBucket (0-7,
+	8-30, 31-90, 90+, or never if a date is missing). The AgeDays and AgeBucket columns can also be chosen with
+	-columns without -stale-days. Example: -stale-days 30 -sort agedays:desc
+	At the end of every run, a summary of the number of devices per org and age bucket (and stale devices, with
+	-stale-days) is printed and logged. With "-o -" it is printed to standard error.
+
+	The optional command-line argument "-format" sets the output format:
+		csv     Comma-separated values with a header row (the default).
+		json    One JSON array of objects, keyed by column name.
+		ndjson  Newline-delimited JSON: one object per line, for loading into dashboards.
+		xlsx    An Excel workbook. Numbers are stored as numbers; the header row is frozen and filterable.
+		html    A web page with one table. Click a column header to sort by it.
+	The optional command-line argument "-o" sets the output file. The default is output.<format>, e.g. output.xlsx.
+	Use "-o -" to write the report to standard output.
+
 Format of userinfo.config:
 	A file with one entry per line:
 		master server url, e.g.: https://master.example.com:4285
@@ -79,48 +153,46 @@ API version compatability note: this software should work with Code42 server ver
 package main
 
 import (
-	"bufio"
-	"crypto/tls"
-	"encoding/csv"
-	"encoding/json"
+	"bytes"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-	"strconv"
-	"strings"
 	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
 )
 
 const (
-	DeviceBackupReport = "/api/DeviceBackupReport?pgSize=1000" // Page size of 1000 is the default and current max as of 5.1.2.
-	Computer           = "/api/Computer"
-	User               = "/api/User?pgSize=99999"
-
-	helpText = "Command line parameters: \n [-active] [-limit <number> ] [-nousers] [-help]\n" +
+	helpText = "Command line parameters: \n [-active] [-limit <number> ] [-nousers] [-workers <number>] [-rps <number>] [-retries <number>] [-retrywait <duration>] [-columns <keys>] [-sort <keys>] [-usage match|rows|columns] [-stale-days <number>] [-format <format>] [-o <path>] [-help]\n" +
 		"USAGE: \nThe -active option filters out deactivated devices from the report.\n" +
 		"The -limit option limits the number of calls made to the Computer resource of the Code42 API. \n" +
 		"These API calls to Computer are needed to fill in some fields of the report, but can be time-consuming. \n" +
 		"For initial testing, it may be useful to limit these calls. \n" +
+		"The -workers option sets how many calls to the Computer resource are made at the same time (default 4). \n" +
+		"The -rps option caps the number of API calls per second made by all workers together (default 10, 0 means no limit). \n" +
+		"The -retries option sets how many times an API call that fails with a dropped connection or server error is retried (default 3). \n" +
+		"The -retrywait option sets the wait before the first retry, e.g. 2s. The wait doubles after each retry. \n" +
+		"The -columns option chooses the columns of the report and their order, e.g. -columns \"email,username,deviceuid=GUID,os,version\". \n" +
+		"Each key may be followed by =Label to change its header. Without -columns the report has its original 13 columns. \n" +
+		"The -sort option sorts the report by one or more columns, e.g. -sort \"orgname,lastconnected:desc\" (default lastconnected, oldest first). \n" +
+		"Dates are compared as points in time. Users without devices always come last. -sort none keeps the order of the API. \n" +
+		"The -usage option chooses how the backup to each destination of a device is reported: match (default) fills SelectedFiles, LastBackup, \n" +
+		"BytesToDo and FilesToDo from the destination of the row; rows adds a row for every other destination of the device, \n" +
+		"in which DeviceStatus, LastCompletedBackup, LastConnected, BackupCompletePercentage, Alerts and the age columns are blank; \n" +
+		"columns adds a group of these four columns per destination. \n" +
+		"The -stale-days option flags devices whose last completed backup or last connection is more than N days ago, \n" +
+		"adding the columns Stale, AgeDays and AgeBucket (0-7, 8-30, 31-90, 90+ or never) to the report. \n" +
+		"A summary of devices per org and age bucket is printed at the end of every run. \n" +
+		"The -format option sets the output format: csv (default), json, ndjson, xlsx or html. \n" +
+		"The -o option sets the output file (default output.<format>, e.g. output.csv). Use -o - to write to standard output. \n" +
 		"The -nousers option tells the program to skip the process of appending users who do not have registered devices to the report. \n" +
 		"Note: when the -active option is specified, the list of users without devices will also include users with deactivated devices. \n" +
 		"If the -active option is not specified, the list of users at the end of the report includes only users who have never had an active device."
 )
 
-type Records [][]string // The datatype that holds the results just before conversion to CSV
-
 var (
-	/* URL of the master server, with port */
-	url      string
-	resource string // Data-type for the resource to call within the API: either DeviceBackup report, User, or Computer
-
-	/* Credentials for master server below.  */
-	username        string
-	password        string
-	testLimitNumber int    // Stores the limit to the number of calls to the computer resource.
-	activeFilter    string // Stores the query that filters out deactivated devices if desired
+	testLimitNumber int // Stores the limit to the number of calls to the computer resource.
 )
 
 /* Complex datastructures defined below */
@@ -131,48 +203,54 @@ type ReportData struct {
 type ReportDataArray []ReportDataRecord
 
 type ReportDataRecord struct {
-	Email string `json:"email"`
-	//Username string `json:"username"`
-	DeviceName               string `json:"deviceName`
-	Status                   string `json:"status"`
+	Email                    string
+	Username                 string
+	DeviceName               string
+	Status                   string
 	SelectedFiles            string // From Computer resource
 	LastBackupDate           string // From Computer resource
-	LastCompletedBackupDate  string `json:"lastCompletedBackupDate"`
-	LastConnectedDate        string `json:"lastConnectedDate"`
+	LastCompletedBackupDate  string
+	LastConnectedDate        string
 	BytesToDo                string // From Computer resource
 	FilesToDo                string // From Computer resource
-	BackupCompletePercentage string `json:"backupCompletePercentage"`
-	AlertStates              string `json:"alertStates"`
-	DestinationName          string `json:destinationName`
-	OrgName                  string `json:"orgName"`
-	UserUid                  string `json:"userUid"`   // Not in report. Used to join data.
-	DeviceUid                string `json:"deviceUid"` // Not in report. Used to find data from the Computer API resource
+	BackupCompletePercentage string
+	AlertStates              string
+	DestinationName          string
+	OrgName                  string
+	OsName                   string // From Computer resource
+	OsVersion                string // From Computer resource
+	ProductVersion           string // From Computer resource
+	AgeDays                  string // Days since the older of LastCompletedBackupDate and LastConnectedDate
+	AgeBucket                string // 0-7, 8-30, 31-90, 90+ or never
+	Stale                    string // yes or no, with -stale-days
+	UserUid                  string // Used to join data. Not in the report unless chosen with -columns.
+	DeviceUid                string // Used to find data from the Computer API resource. Not in the report unless chosen with -columns.
+
+	Usage []client.BackupUsage // From Computer resource: the backup to each destination of the device
+
+	usageOnly bool // Added by -usage rows: only the Computer fields of the destination are filled in
 }
 
-type UsersData struct {
-	Data struct {
-		TotalCount int `json:"totalCount"`
-		Users      []struct {
-			UserUid string `json:"userUid"`
-			Email   string `json:"email"`
-		}
-	} `json:"data"`
-}
-
-type ComputerData struct {
-	Data struct {
-		Guid        string `json:"guid"`
-		BackupUsage []struct {
-			SelectedFiles int    `json:"selectedFiles"`
-			LastBackup    string `json:"lastBackup"`
-			TodoBytes     int    `json:"todoBytes"`
-			TodoFiles     int    `json:"todoFiles"`
-		}
+/* newReportDataRecord copies the DeviceBackupReport fields of a device into a report record */
+func newReportDataRecord(d client.DeviceBackup) ReportDataRecord {
+	return ReportDataRecord{
+		Email:                    d.Email,
+		Username:                 d.Username,
+		DeviceName:               d.DeviceName,
+		Status:                   d.Status,
+		LastCompletedBackupDate:  d.LastCompletedBackupDate,
+		LastConnectedDate:        d.LastConnectedDate,
+		BackupCompletePercentage: d.BackupCompletePercentage,
+		AlertStates:              d.AlertStates,
+		DestinationName:          d.DestinationName,
+		OrgName:                  d.OrgName,
+		UserUid:                  d.UserUid,
+		DeviceUid:                d.DeviceUid,
 	}
 }
 
 func main() {
-	timeStamp := makeTimestamp()
+	timeStamp := client.DateStamp()
 
 	f, err := os.OpenFile("c42ComputerUserReportLogFile"+timeStamp, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
@@ -186,229 +264,186 @@ func main() {
 	activeOnlyArg := flag.Bool("active", false, "If set, shows only active devices. Default is false.")
 	testLimitNumberArg := flag.Int("limit", -1, "Limits the calls to the computer API to this number.")
 	noUsers := flag.Bool("nousers", false, "Do not append users without active or inactive devices.")
+	workersArg := flag.Int("workers", 4, "Number of calls to the Computer API to make at the same time.")
+	rateArg := flag.Float64("rps", 10, "Maximum number of API calls per second, across all workers. 0 means no limit.")
+	retriesArg := flag.Int("retries", client.DefaultRetryPolicy.MaxAttempts-1, "Number of times to retry an API call that fails with a dropped connection or server error.")
+	retryWaitArg := flag.Duration("retrywait", client.DefaultRetryPolicy.BaseDelay, "Wait before the first retry. Doubled for each retry after that.")
+	columnsArg := flag.String("columns", "", "Comma-separated column keys, in order, each optionally key=Label. Default is the original 13 columns.")
+	usageArg := flag.String("usage", usageMatch, "Backup usage per destination: match (one row per report row), rows (one row per device and destination) or columns (a column group per destination).")
+	staleDaysArg := flag.Int("stale-days", 0, "Flag devices that have not completed a backup or connected for more than this many days. 0 means off.")
+	sortArg := flag.String("sort", defaultSort, "Comma-separated column keys to sort by, each optionally :asc or :desc. none keeps the API order.")
+	formatArg := flag.String("format", "csv", "Output format: "+formatNames()+".")
+	outputArg := flag.String("o", "", "Output file. Default is output.<format>. Use - for standard output.")
 	showHelp := flag.Bool("help", false, "Show help.")
 
 	flag.Parse()
 
 	if *showHelp {
 		fmt.Println(helpText)
+		fmt.Println("Columns for -columns:", columnKeys())
 		log.Println("Showing help and exiting.")
 		os.Exit(0)
 	}
 
-	testLimitNumber = *testLimitNumberArg
-
-	/* Create the string that is added to the query to DeviceBackupReport API that filters out deactivated devices */
-
-	if *activeOnlyArg {
-		activeFilter = "&active=true"
+	/* Check the output format now rather than after the API calls */
+	if _, ok := reportFormats[*formatArg]; !ok {
+		fmt.Printf("-format %q is not valid: use %s.\n", *formatArg, formatNames())
+		log.Fatalf("-format %q is not valid.", *formatArg)
+	}
+	columns, err := selectColumns(*columnsArg)
+	if err != nil {
+		fmt.Println("-columns:", err)
+		log.Fatalln("-columns:", err)
+	}
+	if *usageArg != usageMatch && *usageArg != usageRows && *usageArg != usageColumns {
+		fmt.Printf("-usage %q is not valid: use match, rows or columns.\n", *usageArg)
+		log.Fatalf("-usage %q is not valid.", *usageArg)
+	}
+	sortKeys, err := parseSort(*sortArg)
+	if err != nil {
+		fmt.Println("-sort:", err)
+		log.Fatalln("-sort:", err)
+	}
+	if *staleDaysArg < 0 {
+		fmt.Println("-stale-days must be greater than or equal to zero.")
+		log.Fatalln("-stale-days must be greater than or equal to zero.")
+	}
+	if *staleDaysArg > 0 {
+		/* The columns that tell which devices are stale, unless -columns placed them already */
+		for _, key := range []string{"stale", "agedays", "agebucket"} {
+			if !hasColumn(columns, key) {
+				column, _ := findColumn(key)
+				columns = append(columns, column)
+			}
+		}
+	}
+	if *outputArg == "" {
+		*outputArg = "output." + *formatArg
 	}
 
-	/* Read the user authentication info from file userinfo.config */
-	lines, err := readLines("userinfo.config")
+	testLimitNumber = *testLimitNumberArg
 
+	/* Read the user authentication info from file userinfo.config */
+	c42, err := client.NewFromConfigFile("userinfo.config")
 	if err != nil {
-		log.Fatalln("readLines: %s", err)
+		log.Fatalln("Can't read userinfo.config:", err)
 	}
-	if len(lines) < 3 {
-		log.Fatalln("Info is missing from the config file.")
-	}
-	url = strings.Trim(lines[0], " ") // Trimming extra spaces at beginning and end of lines
-	username = strings.Trim(lines[1], " ")
-	password = strings.Trim(lines[2], " ")
+	c42.Retry.MaxAttempts = *retriesArg + 1
+	c42.Retry.BaseDelay = *retryWaitArg
+	c42.Logf = log.Printf
+	c42.SetRateLimit(*rateArg)
+	log.Printf("Using %d workers for the Computer API, at most %v calls per second.", *workersArg, *rateArg)
 
 	/* Retrieve the DeviceBackupReport data, the first part of the report
 	Need to loop and get data page by page until no data is left. Page limit of 1000 hard-coded into API */
 	deviceReportMsg := ReportData{}
-	endDeviceData := false // A flag to signal that the last page of data from DeviceBackup has been reached
-	for page := 1; endDeviceData != true; page++ {
-		deviceReportMsgPage := ReportData{} // Store each page in this variable
-
-		resourcePlusQuery := DeviceBackupReport + "&pgNum=" + strconv.Itoa(page) + activeFilter
-		contents := makeRequest(url, resourcePlusQuery) // Call the function that makes http requests and returns a response in of type []byte
-
-		/* Deserialize the JSON data into a struct */
-		errJson := json.Unmarshal(contents, &deviceReportMsgPage)
-		if errJson != nil {
-			log.Fatalln("Error unmarshalling JSON from device report api:", errJson)
+	var devices []client.DeviceBackup // As returned, for the join with users
+	for pgNum := 1; ; pgNum++ {
+		page, err := c42.DeviceBackupReport(pgNum, *activeOnlyArg)
+		if err != nil {
+			quitOnAPIError("Error retrieving device report", err)
 		}
-
-		if len(deviceReportMsgPage.Data) == 0 {
-			endDeviceData = true
-		} else {
-			for _, pageStruxData := range deviceReportMsgPage.Data {
-				deviceReportMsg.Data = append(deviceReportMsg.Data, pageStruxData)
-			}
+		if len(page) == 0 {
+			break // The last page of data from DeviceBackupReport has been reached
 		}
+		for _, device := range page {
+			deviceReportMsg.Data = append(deviceReportMsg.Data, newReportDataRecord(device))
+		}
+		devices = append(devices, page...)
 	}
 
 	/* Convert the deviceReportMsg data to an array */
 	var reportDataArray ReportDataArray
 
 	reportDataArray = deviceReportMsg.Data
-	reportDataRecord := ReportDataRecord{}
-
-	/* Get missing fields from Computer  */
-	computerMsg := ComputerData{}
 
-	for j, strux := range deviceReportMsg.Data {
-		if testLimitNumber != -1 && j >= testLimitNumber {
-			break
-		}
-
-		/* Get missing info from Computer resource here.
-		Use deviceUid as the key */
-		deviceUid := strux.DeviceUid
-		// log.Println("Retrieving info from Computer resource from device with guid:",deviceUid)
-		query := Computer + "/" + deviceUid + "?idType=guid&incAll=true"
-
-		contents := makeRequest(url, query)
-		errJson := json.Unmarshal(contents, &computerMsg)
-		if errJson != nil {
-			log.Fatalln("Error unmarshalling JSON from the Computer API resource:", errJson)
-		}
-		if len(computerMsg.Data.BackupUsage) != 0 {
-			reportDataArray[j].SelectedFiles = strconv.Itoa(computerMsg.Data.BackupUsage[0].SelectedFiles)
-			reportDataArray[j].LastBackupDate = computerMsg.Data.BackupUsage[0].LastBackup
-			reportDataArray[j].BytesToDo = strconv.Itoa(computerMsg.Data.BackupUsage[0].TodoBytes)
-			reportDataArray[j].FilesToDo = strconv.Itoa(computerMsg.Data.BackupUsage[0].TodoFiles)
-		}
+	/* Get missing fields from Computer, several devices at a time  */
+	computerFailures, err := fetchComputerData(c42, reportDataArray, testLimitNumber, *workersArg)
+	if err != nil {
+		quitOnAPIError("Error retrieving the Computer API resource", err)
 	}
 
 	totalDeviceObjects := len(reportDataArray) // Store total number of devices found for log file
+	if computerFailures > 0 {
+		fmt.Printf("Computer data could not be retrieved for %d devices. Their Computer fields are blank. See log for device GUIDs.\n", computerFailures)
+		log.Printf("Computer data could not be retrieved for %d devices.", computerFailures)
+	}
 
 	/* Any users who have no registered device need to be found and appended to the report */
 	if !*noUsers {
-		userMsg := UsersData{}
-		contents := makeRequest(url, User) // Call makeRequest again to get the data from the User resource of the API
-		/* Deserialize the JSON data into a struct */
-		errJson := json.Unmarshal(contents, &userMsg)
-		if errJson != nil {
-			log.Fatalln("Error unmarshalling JSON from the User API resource:", errJson)
+		users, totalCount, err := c42.AllUsers(client.UserPageSize)
+		if err != nil {
+			quitOnAPIError("Error retrieving the User API resource", err)
+		}
+		log.Printf("Retrieved %d users; the server reports %d.", len(users), totalCount)
+		if len(users) != totalCount {
+			fmt.Printf("Warning: the server reports %d users, but %d were retrieved. The list of users without devices may be incomplete.\n", totalCount, len(users))
+			log.Printf("Warning: the server reports %d users, but %d were retrieved.", totalCount, len(users))
 		}
 
-		for _, strux := range userMsg.Data.Users {
-
-			userGuid := strux.UserUid
-			flag := false
-			for _, struxInner := range deviceReportMsg.Data {
-				if userGuid == struxInner.UserUid {
-					flag = true
-					break
-				}
+		/* Join users to devices on UserUid. The join indexes both lists, so large tenants don't need a scan of every
+		device for every user. */
+		join := client.NewJoin(users, devices)
+		for _, user := range join.UsersWithoutDevices() {
+			if user.Email != "" {
+				reportDataArray = append(reportDataArray, ReportDataRecord{Email: user.Email, Username: user.Username, UserUid: user.UserUid, OrgName: user.OrgName})
 			}
-			if !flag && strux.Email != "" {
-				reportDataArray = append(reportDataArray, reportDataRecord)
-				reportDataArray[len(reportDataArray)-1].Email = strux.Email
-			}
-
 		}
 	}
 
 	deviceReportMsg.Data = reportDataArray
-
-	finalRecords := convertStructToRecords(deviceReportMsg) // store the results in finalRecords variable which is an array of string arrays
-	/* Create and write the CSV file  */
-	csvfile, csv_err := os.Create("output.csv")
-	if csv_err != nil {
-		log.Fatalln("Error creating CSV file:", csv_err)
+	/* Report the backup to every destination of a device, if asked to */
+	switch *usageArg {
+	case usageRows:
+		deviceReportMsg.Data = usageRowsReport(deviceReportMsg.Data)
+	case usageColumns:
+		deviceReportMsg.Data, columns = usageColumnsReport(deviceReportMsg.Data, columns)
 	}
+	setAges(deviceReportMsg.Data, time.Now(), *staleDaysArg)
+	sortReport(deviceReportMsg.Data, sortKeys)
 
-	defer csvfile.Close()
-	w := csv.NewWriter(csvfile)
-	w.WriteAll(finalRecords) // calls Flush internally
-
-	if write_err := w.Error(); write_err != nil {
-		log.Fatalln("error writing csv:", write_err)
+	/* Write the report in the requested format */
+	if err := writeReportFile(*outputArg, *formatArg, columns, deviceReportMsg.Data); err != nil {
+		fmt.Println("Error writing the report:", err)
+		log.Fatalln("Error writing the report:", err)
 	}
+	log.Printf("Report written to %s as %s.", *outputArg, *formatArg)
+
+	/* Summary of device ages, on standard error if the report itself went to standard output */
+	var summary bytes.Buffer
+	writeAgeSummary(&summary, deviceReportMsg.Data, *staleDaysArg)
+	if *outputArg == "-" {
+		os.Stderr.Write(summary.Bytes())
+	} else {
+		os.Stdout.Write(summary.Bytes())
+	}
+	log.Print("\n" + summary.String())
 	log.Println("Total number of device objects:", totalDeviceObjects)
 	log.Println("Report generated. Exiting")
 }
 
-func convertStructToRecords(data ReportData) Records {
-	/* This function converts data that is in the form defined in the datatype ReportData into an array of string arrays
-	as defined in the data type Records */
-
-	converted := make(Records, len(data.Data)+1)
-
-	/* Add headers to columns */
-	headers := [13]string{"Email", "DeviceName", "DeviceStatus", "SelectedFiles", "LastBackup", "LastCompletedBackup", "LastConnected", "BytesToDo", "FilesToDo", "BackupCompletePercentage", "Alerts", "Destination", "OrgName"}
-	for _, header := range headers {
-		converted[0] = append(converted[0], header)
-	}
-
-	for index, strux := range data.Data {
-
-		index++ // Increment index because of header added above
-
-		converted[index] = append(converted[index], strux.Email)
-		converted[index] = append(converted[index], strux.DeviceName)
-		converted[index] = append(converted[index], strux.Status)
-		converted[index] = append(converted[index], strux.SelectedFiles)
-		converted[index] = append(converted[index], strux.LastBackupDate)
-		converted[index] = append(converted[index], strux.LastCompletedBackupDate)
-		converted[index] = append(converted[index], strux.LastConnectedDate)
-		converted[index] = append(converted[index], strux.BytesToDo)
-		converted[index] = append(converted[index], strux.FilesToDo)
-		converted[index] = append(converted[index], strux.BackupCompletePercentage)
-		converted[index] = append(converted[index], strux.AlertStates)
-		converted[index] = append(converted[index], strux.DestinationName)
-		converted[index] = append(converted[index], strux.OrgName)
-
-	}
-
-	return converted
-
-}
-
-func makeRequest(url, resource string) []byte {
-	// Build the transport method for the request. Skip ssl errors
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+/* writeReportFile writes the report to the file at path, or to standard output if path is "-" */
+func writeReportFile(path, format string, columns []reportColumn, data ReportDataArray) error {
+	if path == "-" {
+		return writeReport(format, os.Stdout, columns, data)
 	}
-
-	client := &http.Client{Transport: tr} // Create an http client
-
-	/* Build a request to the DeviceBackupReport resource */
-	fullPath := url + resource
-
-	req, err := http.NewRequest("GET", fullPath, nil)
-	req.SetBasicAuth(username, password)
-	resp, err := client.Do(req) // Perform the GET request
+	file, err := os.Create(path)
 	if err != nil {
-		log.Fatalf("Error making request: %s", err)
+		return err
 	}
-
-	/* Get the results */
-	defer resp.Body.Close()
-	contents, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalf("%s", err)
+	if err := writeReport(format, file, columns, data); err != nil {
+		file.Close()
+		return err
 	}
-
-	return contents
-
+	return file.Close()
 }
 
-func readLines(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+/* quitOnAPIError prints and logs an error returned by the Code42 API, with a hint for the usual causes, and quits */
+func quitOnAPIError(context string, err error) {
+	msg := fmt.Sprintf("%s: %v", context, err)
+	if hint := client.ErrorHint(err); hint != "" {
+		msg += "\n" + hint
 	}
-	return lines, scanner.Err()
-
-}
-
-/* This function returns a string timestamp for loggin */
-func makeTimestamp() string {
-	timenow := time.Now().String()
-	return timenow[0:10]
+	fmt.Println(msg)
+	log.Fatalln(msg)
 }
-
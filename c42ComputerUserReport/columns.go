@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reportColumn is one column of the report: the key -columns and -sort choose it by, its header, the kind of values
+// it holds, and how to get its value from a record.
+type reportColumn struct {
+	Key    string
+	Header string
+	Kind   columnKind
+	Value  func(r ReportDataRecord) string
+}
+
+/* columnKind tells -sort how to compare the values of a column */
+type columnKind int
+
+const (
+	textColumn   columnKind = iota
+	numberColumn            // Compared as numbers, e.g. 9 before 10
+	timeColumn              // API timestamps, compared as points in time whatever their UTC offset
+)
+
+// columnRegistry lists every column the report can have. The report has defaultColumns unless -columns chooses
+// others. To add a column, add its field to ReportDataRecord and an entry here.
+var columnRegistry = []reportColumn{
+	{"email", "Email", textColumn, func(r ReportDataRecord) string { return r.Email }},
+	{"username", "Username", textColumn, func(r ReportDataRecord) string { return r.Username }},
+	{"devicename", "DeviceName", textColumn, func(r ReportDataRecord) string { return r.DeviceName }},
+	{"devicestatus", "DeviceStatus", textColumn, func(r ReportDataRecord) string { return r.Status }},
+	{"selectedfiles", "SelectedFiles", numberColumn, func(r ReportDataRecord) string { return r.SelectedFiles }},
+	{"lastbackup", "LastBackup", timeColumn, func(r ReportDataRecord) string { return r.LastBackupDate }},
+	{"lastcompletedbackup", "LastCompletedBackup", timeColumn, func(r ReportDataRecord) string { return r.LastCompletedBackupDate }},
+	{"lastconnected", "LastConnected", timeColumn, func(r ReportDataRecord) string { return r.LastConnectedDate }},
+	{"bytestodo", "BytesToDo", numberColumn, func(r ReportDataRecord) string { return r.BytesToDo }},
+	{"filestodo", "FilesToDo", numberColumn, func(r ReportDataRecord) string { return r.FilesToDo }},
+	{"backupcompletepercentage", "BackupCompletePercentage", numberColumn, func(r ReportDataRecord) string { return r.BackupCompletePercentage }},
+	{"alerts", "Alerts", textColumn, func(r ReportDataRecord) string { return r.AlertStates }},
+	{"destination", "Destination", textColumn, func(r ReportDataRecord) string { return r.DestinationName }},
+	{"orgname", "OrgName", textColumn, func(r ReportDataRecord) string { return r.OrgName }},
+	{"os", "OS", textColumn, func(r ReportDataRecord) string { return r.OsName }},
+	{"osversion", "OSVersion", textColumn, func(r ReportDataRecord) string { return r.OsVersion }},
+	{"version", "Version", textColumn, func(r ReportDataRecord) string { return r.ProductVersion }},
+	{"agedays", "AgeDays", numberColumn, func(r ReportDataRecord) string { return r.AgeDays }},
+	{"agebucket", "AgeBucket", textColumn, func(r ReportDataRecord) string { return r.AgeBucket }},
+	{"stale", "Stale", textColumn, func(r ReportDataRecord) string { return r.Stale }},
+	{"useruid", "UserUid", textColumn, func(r ReportDataRecord) string { return r.UserUid }},
+	{"deviceuid", "DeviceGuid", textColumn, func(r ReportDataRecord) string { return r.DeviceUid }},
+}
+
+/* defaultColumns are the columns of the report when -columns is not given: the original 13 */
+const defaultColumns = "email,devicename,devicestatus,selectedfiles,lastbackup,lastcompletedbackup,lastconnected," +
+	"bytestodo,filestodo,backupcompletepercentage,alerts,destination,orgname"
+
+/* columnKeys returns the keys of every column, in registry order, for help and error messages */
+func columnKeys() string {
+	var keys []string
+	for _, column := range columnRegistry {
+		keys = append(keys, column.Key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+/* hasColumn reports whether columns include the one with the given key */
+func hasColumn(columns []reportColumn, key string) bool {
+	for _, column := range columns {
+		if column.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+/* findColumn returns the registry entry for key, ignoring case */
+func findColumn(key string) (reportColumn, bool) {
+	for _, column := range columnRegistry {
+		if strings.EqualFold(column.Key, key) {
+			return column, true
+		}
+	}
+	return reportColumn{}, false
+}
+
+// selectColumns returns the columns listed in spec, in its order. spec is a comma-separated list of column keys,
+// each optionally followed by =Label to replace its header, e.g. "email=User Email,devicename,deviceuid=GUID".
+// An empty spec selects the default columns.
+func selectColumns(spec string) ([]reportColumn, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = defaultColumns
+	}
+
+	var columns []reportColumn
+	headers := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		key, label := item, ""
+		if i := strings.Index(item, "="); i >= 0 {
+			key, label = item[:i], strings.TrimSpace(item[i+1:])
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		column, ok := findColumn(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q: use %s", key, columnKeys())
+		}
+		if label != "" {
+			column.Header = label
+		}
+		if headers[column.Header] {
+			return nil, fmt.Errorf("column header %q is used twice", column.Header)
+		}
+		headers[column.Header] = true
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns chosen")
+	}
+	return columns, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* fetchComputerData fills in the fields of the report that come from the Computer resource, for the first limit
+records (all of them if limit is -1). The lookups run in a pool of workers goroutines. Each result is written back
+to its own record, so the report keeps the DeviceBackupReport order whatever order the lookups finish in.
+
+It returns the number of devices whose Computer data could not be retrieved, even after retrying; their Computer
+fields are left blank. If the server refuses the credentials, the remaining lookups are abandoned and the
+error is returned. */
+func fetchComputerData(c42 *client.Client, records ReportDataArray, limit, workers int) (int, error) {
+	count := len(records)
+	if limit != -1 && limit < count {
+		count = limit
+	}
+	if count < 0 {
+		count = 0 // Any other negative limit means no lookups, as it always has
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, count) // One slot per record, so workers never share a variable
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var authErr error
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				/* Get missing info from Computer resource here.
+				Use deviceUid as the key */
+				computer, err := c42.Computer(records[j].DeviceUid)
+				if err != nil {
+					errs[j] = err
+					if errors.Is(err, client.ErrAuth) {
+						stopOnce.Do(func() {
+							authErr = err
+							close(stop)
+						})
+					}
+					continue
+				}
+				records[j].OsName = computer.OsName
+				records[j].OsVersion = computer.OsVersion
+				records[j].ProductVersion = computer.ProductVersion
+				records[j].Usage = computer.BackupUsage
+				if usage, ok := records[j].usageFor(records[j].DestinationName); ok {
+					records[j].setUsage(usage)
+				} else {
+					log.Printf("Device %v has no backupUsage for destination %q; its Computer backup fields are left blank", records[j].DeviceUid, records[j].DestinationName)
+				}
+			}
+		}()
+	}
+
+feed:
+	for j := 0; j < count; j++ {
+		select {
+		case jobs <- j:
+		case <-stop:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if authErr != nil {
+		return 0, authErr
+	}
+
+	/* Logged after the pool is done, so the log lists failed devices in report order */
+	failures := 0
+	for j, err := range errs {
+		if err != nil {
+			log.Printf("Error retrieving the Computer API resource for device %v: %v", records[j].DeviceUid, err)
+			failures++
+		}
+	}
+	return failures, nil
+}
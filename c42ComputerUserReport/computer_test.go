@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func TestFetchComputerDataNegativeLimit(t *testing.T) {
+	records := ReportDataArray{{DeviceUid: "1"}, {DeviceUid: "2"}}
+	for _, limit := range []int{0, -2, -100} {
+		failed, err := fetchComputerData(nil, records, limit, 4) // No lookups, so the client is never used
+		if failed != 0 || err != nil {
+			t.Errorf("limit %d: got %d, %v", limit, failed, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"time"
+)
+
+// htmlWriter writes the report as a self-contained HTML page with one table. Clicking a column header sorts the
+// table by that column; clicking it again reverses the order.
+type htmlWriter struct {
+	w       *bufio.Writer
+	columns []reportColumn
+	started bool
+}
+
+func newHTMLWriter(w io.Writer, columns []reportColumn) reportWriter {
+	return &htmlWriter{w: bufio.NewWriter(w), columns: columns}
+}
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Code42 Computer User Report</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 3px 6px; text-align: left; }
+th { background: #eee; cursor: pointer; position: sticky; top: 0; }
+tr:nth-child(even) td { background: #f8f8f8; }
+</style>
+</head>
+<body>
+`
+
+/* Sorts the table when a header is clicked: numbers as numbers, everything else as text; blanks last */
+const htmlTail = `</tbody>
+</table>
+<script>
+document.querySelectorAll("th").forEach(function (th, col) {
+	th.addEventListener("click", function () {
+		var tbody = document.querySelector("tbody");
+		var asc = th.getAttribute("data-order") !== "asc";
+		document.querySelectorAll("th").forEach(function (h) { h.removeAttribute("data-order"); });
+		th.setAttribute("data-order", asc ? "asc" : "desc");
+		var rows = Array.prototype.slice.call(tbody.rows);
+		rows.sort(function (a, b) {
+			var x = a.cells[col].textContent, y = b.cells[col].textContent;
+			if (x === "" || y === "") { return (x === "") - (y === ""); }
+			var nx = Number(x), ny = Number(y);
+			var c = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+			return asc ? c : -c;
+		});
+		rows.forEach(function (r) { tbody.appendChild(r); });
+	});
+});
+</script>
+</body>
+</html>
+`
+
+func (hw *htmlWriter) start() {
+	hw.started = true
+	hw.w.WriteString(htmlHead)
+	hw.w.WriteString("<p>Generated " + html.EscapeString(time.Now().Format(time.RFC1123)) + ". Click a column header to sort.</p>\n")
+	hw.w.WriteString("<table>\n<thead><tr>")
+	for _, column := range hw.columns {
+		hw.w.WriteString("<th>" + html.EscapeString(column.Header) + "</th>")
+	}
+	hw.w.WriteString("</tr></thead>\n<tbody>\n")
+}
+
+func (hw *htmlWriter) Write(r ReportDataRecord) error {
+	if !hw.started {
+		hw.start()
+	}
+	hw.w.WriteString("<tr>")
+	for _, column := range hw.columns {
+		hw.w.WriteString("<td>" + html.EscapeString(column.Value(r)) + "</td>")
+	}
+	_, err := hw.w.WriteString("</tr>\n")
+	return err
+}
+
+func (hw *htmlWriter) Close() error {
+	if !hw.started {
+		hw.start()
+	}
+	hw.w.WriteString(htmlTail)
+	return hw.w.Flush()
+}
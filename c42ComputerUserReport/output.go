@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// reportWriter writes the report in one output format. Write is called once per record, in report order, and Close
+// once at the end; Close does not close the underlying file.
+type reportWriter interface {
+	Write(r ReportDataRecord) error
+	Close() error
+}
+
+/* reportFormats maps each -format value to the constructor of its writer */
+var reportFormats = map[string]func(w io.Writer, columns []reportColumn) reportWriter{
+	"csv":    newCSVWriter,
+	"json":   newJSONWriter,
+	"ndjson": newNDJSONWriter,
+	"xlsx":   newXLSXWriter,
+	"html":   newHTMLWriter,
+}
+
+/* formatNames returns the -format values, sorted, for help and error messages */
+func formatNames() string {
+	var names []string
+	for name := range reportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+/* newReportWriter returns the writer for format, writing the given columns to w */
+func newReportWriter(format string, w io.Writer, columns []reportColumn) (reportWriter, error) {
+	newWriter, ok := reportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("format %q is not valid: use %s", format, formatNames())
+	}
+	return newWriter(w, columns), nil
+}
+
+/* writeReport writes every record of data with a writer for format */
+func writeReport(format string, w io.Writer, columns []reportColumn, data ReportDataArray) error {
+	rw, err := newReportWriter(format, w, columns)
+	if err != nil {
+		return err
+	}
+	for _, record := range data {
+		if err := rw.Write(record); err != nil {
+			return err
+		}
+	}
+	return rw.Close()
+}
+
+/* csvWriter writes the report as CSV with a header row, the format of the original output.csv */
+type csvWriter struct {
+	w       *csv.Writer
+	columns []reportColumn
+	started bool
+}
+
+func newCSVWriter(w io.Writer, columns []reportColumn) reportWriter {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns}
+}
+
+func (cw *csvWriter) Write(r ReportDataRecord) error {
+	if !cw.started {
+		cw.writeHeader()
+	}
+	row := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		row[i] = column.Value(r)
+	}
+	return cw.w.Write(row)
+}
+
+func (cw *csvWriter) writeHeader() {
+	cw.started = true
+	header := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		header[i] = column.Header
+	}
+	cw.w.Write(header)
+}
+
+func (cw *csvWriter) Close() error {
+	if !cw.started {
+		cw.writeHeader() // An empty report still has its header
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+/* jsonObject encodes a record as a JSON object whose keys are the column headers, in column order */
+func jsonObject(columns []reportColumn, r ReportDataRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(column.Header)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(column.Value(r))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+/* jsonWriter writes the report as one JSON array of objects */
+type jsonWriter struct {
+	w       *bufio.Writer
+	columns []reportColumn
+	count   int
+}
+
+func newJSONWriter(w io.Writer, columns []reportColumn) reportWriter {
+	return &jsonWriter{w: bufio.NewWriter(w), columns: columns}
+}
+
+func (jw *jsonWriter) Write(r ReportDataRecord) error {
+	object, err := jsonObject(jw.columns, r)
+	if err != nil {
+		return err
+	}
+	if jw.count == 0 {
+		jw.w.WriteString("[\n")
+	} else {
+		jw.w.WriteString(",\n")
+	}
+	jw.count++
+	_, err = jw.w.Write(object)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	if jw.count == 0 {
+		jw.w.WriteString("[")
+	}
+	jw.w.WriteString("\n]\n")
+	return jw.w.Flush()
+}
+
+/* ndjsonWriter writes the report as newline-delimited JSON: one object per line */
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []reportColumn
+}
+
+func newNDJSONWriter(w io.Writer, columns []reportColumn) reportWriter {
+	return &ndjsonWriter{w: bufio.NewWriter(w), columns: columns}
+}
+
+func (nw *ndjsonWriter) Write(r ReportDataRecord) error {
+	object, err := jsonObject(nw.columns, r)
+	if err != nil {
+		return err
+	}
+	nw.w.Write(object)
+	return nw.w.WriteByte('\n')
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* defaultSort is the order of the report when -sort is not given, as first requested by the customer on 05-17-2016 */
+const defaultSort = "lastconnected"
+
+/* sortKey is one key of -sort: a column and its direction */
+type sortKey struct {
+	column     reportColumn
+	descending bool
+}
+
+// parseSort reads -sort: a comma-separated list of column keys, each optionally followed by :asc or :desc, e.g.
+// "orgname,lastconnected:desc". Later keys break ties in earlier ones. "none" keeps the order of the API.
+func parseSort(spec string) ([]sortKey, error) {
+	if strings.TrimSpace(spec) == "none" {
+		return nil, nil
+	}
+	var keys []sortKey
+	for _, item := range strings.Split(spec, ",") {
+		name, direction := strings.TrimSpace(item), "asc"
+		if i := strings.Index(name, ":"); i >= 0 {
+			name, direction = strings.TrimSpace(name[:i]), strings.ToLower(strings.TrimSpace(name[i+1:]))
+		}
+		if name == "" {
+			continue
+		}
+		column, ok := findColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q: use %s", name, columnKeys())
+		}
+		if direction != "asc" && direction != "desc" {
+			return nil, fmt.Errorf("%q: direction must be asc or desc", item)
+		}
+		keys = append(keys, sortKey{column: column, descending: direction == "desc"})
+	}
+	return keys, nil
+}
+
+/* parseReportTime reads a timestamp as returned by the API. The second layout is a fallback for other API versions. */
+func parseReportTime(value string) (time.Time, error) {
+	t, err := time.Parse(client.ArchiveTimeFormat, value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+	}
+	return t, err
+}
+
+// compareValues returns -1, 0 or 1 as a is before, the same as or after b, according to kind. Values that can't
+// be read as the kind of the column are compared as text, after the ones that can.
+func compareValues(kind columnKind, a, b string) int {
+	switch kind {
+	case numberColumn:
+		x, errX := strconv.ParseFloat(a, 64)
+		y, errY := strconv.ParseFloat(b, 64)
+		if errX == nil && errY == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+		if (errX == nil) != (errY == nil) {
+			if errX == nil {
+				return -1
+			}
+			return 1
+		}
+	case timeColumn:
+		x, errX := parseReportTime(a)
+		y, errY := parseReportTime(b)
+		if errX == nil && errY == nil {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		}
+		if (errX == nil) != (errY == nil) {
+			if errX == nil {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// sortReport sorts the report by keys. Users without devices, which only have user fields, always come after every
+// device, and blank values come last in either direction, so that a descending sort doesn't put them first. The
+// sort is stable: records equal on every key keep their API order.
+func sortReport(data ReportDataArray, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		a, b := data[i], data[j]
+		if noDeviceA, noDeviceB := a.DeviceUid == "", b.DeviceUid == ""; noDeviceA != noDeviceB {
+			return noDeviceB
+		}
+		for _, key := range keys {
+			x, y := key.column.Value(a), key.column.Value(b)
+			if (x == "") != (y == "") {
+				return y == ""
+			}
+			c := compareValues(key.column.Kind, x, y)
+			if key.descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
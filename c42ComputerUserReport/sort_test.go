@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		kind columnKind
+		a, b string
+		want int
+	}{
+		{numberColumn, "9", "10", -1}, // Not as text
+		{numberColumn, "10", "10.0", 0},
+		{numberColumn, "99.5", "100", -1},
+		{numberColumn, "-1", "0", -1},
+		{numberColumn, "5", "n/a", -1}, // Numbers before values that aren't
+		{numberColumn, "n/a", "5", 1},
+		{numberColumn, "abc", "ABD", -1},
+		{timeColumn, "2026-10-01T10:00:00.000-05:00", "2026-10-01T11:00:00.000-05:00", -1},
+		{timeColumn, "2026-10-01T10:00:00.000-05:00", "2026-10-01T15:00:00.000+00:00", 0}, // Same point in time
+		{timeColumn, "2026-10-01T10:00:00.000-05:00", "2026-10-01T14:00:00Z", 1},
+		{timeColumn, "2026-10-01T10:00:00.000-05:00", "never", -1},
+		{timeColumn, "never", "2026-10-01T10:00:00.000-05:00", 1},
+		{textColumn, "Sales", "sales", 0},
+		{textColumn, "Eng", "sales", -1},
+		{textColumn, "9", "10", 1}, // Text, so "9" is after "10"
+	}
+	for _, test := range tests {
+		if got := compareValues(test.kind, test.a, test.b); got != test.want {
+			t.Errorf("%v %q, %q: got %d, want %d", test.kind, test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSortReport(t *testing.T) {
+	data := func() ReportDataArray {
+		return ReportDataArray{
+			{DeviceUid: "1", DeviceName: "blank"},
+			{DeviceUid: "2", DeviceName: "old", LastConnectedDate: "2026-01-01T10:00:00.000-05:00"},
+			{DeviceName: "user without device"},
+			{DeviceUid: "3", DeviceName: "new", LastConnectedDate: "2026-10-01T10:00:00.000-05:00"},
+			{DeviceUid: "4", DeviceName: "middle", LastConnectedDate: "2026-05-01T10:00:00.000-05:00"},
+		}
+	}
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"lastconnected", "old,middle,new,blank,user without device"},
+		{"lastconnected:desc", "new,middle,old,blank,user without device"},
+		{"devicename:desc", "old,new,middle,blank,user without device"},
+		{"none", "blank,old,user without device,new,middle"},
+	}
+	for _, test := range tests {
+		keys, err := parseSort(test.sort)
+		if err != nil {
+			t.Fatalf("%q: %v", test.sort, err)
+		}
+		report := data()
+		sortReport(report, keys)
+		var names []string
+		for _, r := range report {
+			names = append(names, r.DeviceName)
+		}
+		if got := strings.Join(names, ","); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.sort, got, test.want)
+		}
+	}
+}
+
+func TestParseSortErrors(t *testing.T) {
+	for _, spec := range []string{"nosuchcolumn", "lastconnected:up"} {
+		if _, err := parseSort(spec); err == nil {
+			t.Errorf("%q: want an error", spec)
+		}
+	}
+}
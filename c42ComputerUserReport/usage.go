@@ -0,0 +1,138 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* Values of -usage */
+const (
+	usageMatch   = "match"
+	usageRows    = "rows"
+	usageColumns = "columns"
+)
+
+/* usageFor returns the backup of the device to the named destination. It returns false if the device has none. */
+func (r ReportDataRecord) usageFor(destinationName string) (client.BackupUsage, bool) {
+	for _, usage := range r.Usage {
+		if strings.EqualFold(usage.TargetComputerName, destinationName) {
+			return usage, true
+		}
+	}
+	return client.BackupUsage{}, false
+}
+
+/* setUsage fills the Computer fields of the record from the backup to one destination */
+func (r *ReportDataRecord) setUsage(usage client.BackupUsage) {
+	r.SelectedFiles = strconv.Itoa(usage.SelectedFiles)
+	r.LastBackupDate = usage.LastBackup
+	r.BytesToDo = strconv.Itoa(usage.TodoBytes)
+	r.FilesToDo = strconv.Itoa(usage.TodoFiles)
+}
+
+// usageRowsReport adds a row for every destination a device backs up to that has no row in the report yet, right
+// after the device's existing rows. The added rows have the destination's Computer fields. The fields that
+// DeviceBackupReport gives per destination, Status, LastCompletedBackupDate, LastConnectedDate,
+// BackupCompletePercentage and AlertStates, are blank, and so are the age fields that setAges derives from them.
+func usageRowsReport(data ReportDataArray) ReportDataArray {
+	listed := map[string]bool{} // Device and destination pairs that have a row
+	for _, r := range data {
+		listed[r.DeviceUid+"\x00"+strings.ToLower(r.DestinationName)] = true
+	}
+
+	var expanded ReportDataArray
+	for _, r := range data {
+		expanded = append(expanded, r)
+		for _, usage := range r.Usage {
+			key := r.DeviceUid + "\x00" + strings.ToLower(usage.TargetComputerName)
+			if listed[key] {
+				continue
+			}
+			listed[key] = true
+			extra := r
+			extra.DestinationName = usage.TargetComputerName
+			extra.Status = ""
+			extra.LastCompletedBackupDate = ""
+			extra.LastConnectedDate = ""
+			extra.BackupCompletePercentage = ""
+			extra.AlertStates = ""
+			extra.usageOnly = true
+			extra.setUsage(usage)
+			expanded = append(expanded, extra)
+		}
+	}
+	return expanded
+}
+
+// usageColumnsReport keeps one row per device, the first, and adds a group of four columns per destination, sorted
+// by destination name, to columns. Rows of users without devices are kept as they are.
+func usageColumnsReport(data ReportDataArray, columns []reportColumn) (ReportDataArray, []reportColumn) {
+	var collapsed ReportDataArray
+	seen := map[string]bool{}
+	destinations := map[string]string{} // Name as reported, by lowercase name
+	for _, r := range data {
+		if r.DeviceUid != "" {
+			if seen[r.DeviceUid] {
+				continue
+			}
+			seen[r.DeviceUid] = true
+		}
+		collapsed = append(collapsed, r)
+		for _, usage := range r.Usage {
+			if _, ok := destinations[strings.ToLower(usage.TargetComputerName)]; !ok {
+				destinations[strings.ToLower(usage.TargetComputerName)] = usage.TargetComputerName
+			}
+		}
+	}
+
+	var names []string
+	for _, name := range destinations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		columns = append(columns, destinationUsageColumns(name)...)
+	}
+	return collapsed, columns
+}
+
+/* destinationUsageColumns returns the columns with the backup of each device to the named destination */
+func destinationUsageColumns(name string) []reportColumn {
+	usage := func(r ReportDataRecord) (client.BackupUsage, bool) {
+		for _, u := range r.Usage {
+			if strings.EqualFold(u.TargetComputerName, name) {
+				return u, true
+			}
+		}
+		return client.BackupUsage{}, false
+	}
+	return []reportColumn{
+		{"", name + " SelectedFiles", numberColumn, func(r ReportDataRecord) string {
+			if u, ok := usage(r); ok {
+				return strconv.Itoa(u.SelectedFiles)
+			}
+			return ""
+		}},
+		{"", name + " LastBackup", timeColumn, func(r ReportDataRecord) string {
+			if u, ok := usage(r); ok {
+				return u.LastBackup
+			}
+			return ""
+		}},
+		{"", name + " BytesToDo", numberColumn, func(r ReportDataRecord) string {
+			if u, ok := usage(r); ok {
+				return strconv.Itoa(u.TodoBytes)
+			}
+			return ""
+		}},
+		{"", name + " FilesToDo", numberColumn, func(r ReportDataRecord) string {
+			if u, ok := usage(r); ok {
+				return strconv.Itoa(u.TodoFiles)
+			}
+			return ""
+		}},
+	}
+}
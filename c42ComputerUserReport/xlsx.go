@@ -0,0 +1,124 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// xlsxWriter writes the report as an Excel workbook with one worksheet. The workbook is a zip of XML parts, so it
+// can only be written once every row is known: rows are kept in memory until Close. Values that are plain numbers
+// are stored as numbers, so that they can be summed and sorted in Excel; everything else is stored as text.
+type xlsxWriter struct {
+	w       io.Writer
+	columns []reportColumn
+	rows    bytes.Buffer // <row> elements of the worksheet
+	count   int          // Rows written, including the header
+}
+
+func newXLSXWriter(w io.Writer, columns []reportColumn) reportWriter {
+	xw := &xlsxWriter{w: w, columns: columns}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	xw.writeRow(header, false)
+	return xw
+}
+
+/* xlsxNumber matches values stored as numbers. Leading zeros, as in IDs, keep a value text. */
+var xlsxNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]{0,14})(\.[0-9]+)?$`)
+
+/* xlsxColumnName returns the spreadsheet name of column i, counting from 0: A, B, ..., Z, AA, ... */
+func xlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func (xw *xlsxWriter) writeRow(values []string, numbers bool) {
+	xw.count++
+	ref := strconv.Itoa(xw.count)
+	xw.rows.WriteString(`<row r="` + ref + `">`)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		cell := xlsxColumnName(i) + ref
+		if numbers && xlsxNumber.MatchString(value) {
+			xw.rows.WriteString(`<c r="` + cell + `"><v>` + value + `</v></c>`)
+			continue
+		}
+		xw.rows.WriteString(`<c r="` + cell + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(&xw.rows, []byte(value))
+		xw.rows.WriteString(`</t></is></c>`)
+	}
+	xw.rows.WriteString("</row>")
+}
+
+func (xw *xlsxWriter) Write(r ReportDataRecord) error {
+	values := make([]string, len(xw.columns))
+	for i, column := range xw.columns {
+		values[i] = column.Value(r)
+	}
+	xw.writeRow(values, true)
+	return nil
+}
+
+/* The fixed parts of a minimal workbook */
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	/* The header row is frozen and has an autofilter, so the sheet can be sorted and filtered right away */
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<sheetData>`
+)
+
+func (xw *xlsxWriter) Close() error {
+	z := zip.NewWriter(xw.w)
+	lastColumn, lastRow := xlsxColumnName(len(xw.columns)-1), strconv.Itoa(xw.count)
+	sheet := xlsxSheetStart + xw.rows.String() + `</sheetData>` +
+		`<autoFilter ref="A1:` + lastColumn + lastRow + `"/></worksheet>`
+	workbook := xlsxWorkbookStart + `<definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">` +
+		`Report!$A$1:$` + lastColumn + `$` + lastRow + `</definedName></definedNames></workbook>`
+	parts := []struct{ name, contents string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", sheet},
+	}
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.contents); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
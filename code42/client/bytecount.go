@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ByteCount is a number of bytes as returned by the API, which sends some, such as coldBytes, as a string for
+// PROVIDER destinations and as a number for CLUSTER destinations, and sometimes as null. It decodes all three
+// exactly, even beyond the precision of a float64.
+//
+// A value that is not a whole number of bytes does not fail the decoding of the rest of the message: Err is set
+// instead, and Raw holds the value, so that the caller can warn about it rather than guess.
+type ByteCount struct {
+	Bytes int64
+	Null  bool   // The API sent null, or an empty string
+	Raw   string // The value as sent, if it could not be read
+	Err   error
+}
+
+// Known reports whether the API sent a number of bytes that could be read.
+func (b ByteCount) Known() bool {
+	return !b.Null && b.Err == nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *ByteCount) UnmarshalJSON(data []byte) error {
+	*b = ByteCount{}
+	data = bytes.TrimSpace(data)
+	value := string(data)
+	switch {
+	case value == "null":
+		b.Null = true
+		return nil
+	case strings.HasPrefix(value, `"`):
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			b.Null = true
+			return nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		b.Bytes = n
+		return nil
+	}
+	/* Numbers such as 1.5e9 or 1234.0 are still exact if they are whole */
+	if f, _, err := big.ParseFloat(value, 10, 128, big.ToNearestEven); err == nil && f.IsInt() {
+		if n, accuracy := f.Int64(); accuracy == big.Exact {
+			b.Bytes = n
+			return nil
+		}
+	}
+	b.Raw = value
+	b.Err = fmt.Errorf("%q is not a whole number of bytes", value)
+	return nil
+}
+
+// String returns the number of bytes, "null", or the value as sent if it could not be read.
+func (b ByteCount) String() string {
+	switch {
+	case b.Null:
+		return "null"
+	case b.Err != nil:
+		return b.Raw
+	}
+	return strconv.FormatInt(b.Bytes, 10)
+}
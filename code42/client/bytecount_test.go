@@ -0,0 +1,68 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestByteCountUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json  string
+		bytes int64
+		null  bool
+		bad   bool // Err is set and Raw holds the value
+	}{
+		{json: `0`, bytes: 0},
+		{json: `123456789`, bytes: 123456789},
+		{json: `"123456789"`, bytes: 123456789},
+		{json: `" 42 "`, bytes: 42},
+		{json: `-5`, bytes: -5},
+		{json: `9223372036854775807`, bytes: 9223372036854775807},
+		{json: `"9223372036854775807"`, bytes: 9223372036854775807},
+		{json: `9007199254740993`, bytes: 9007199254740993}, // Not exact as a float64
+		{json: `1.5e9`, bytes: 1500000000},
+		{json: `"1234.0"`, bytes: 1234},
+		{json: `null`, null: true},
+		{json: `""`, null: true},
+		{json: `"  "`, null: true},
+		{json: `9223372036854775808`, bad: true},
+		{json: `"18446744073709551616"`, bad: true},
+		{json: `1e30`, bad: true},
+		{json: `12.5`, bad: true},
+		{json: `"12 GB"`, bad: true},
+	}
+	for _, test := range tests {
+		var b ByteCount
+		if err := json.Unmarshal([]byte(test.json), &b); err != nil {
+			t.Errorf("%s: unexpected error %v", test.json, err)
+			continue
+		}
+		switch {
+		case test.bad:
+			if b.Err == nil || b.Raw == "" || b.Known() {
+				t.Errorf("%s: got %+v, want an unreadable value", test.json, b)
+			}
+		case test.null:
+			if !b.Null || b.Known() || b.String() != "null" {
+				t.Errorf("%s: got %+v, want null", test.json, b)
+			}
+		default:
+			if b.Err != nil || b.Null || b.Bytes != test.bytes || !b.Known() {
+				t.Errorf("%s: got %+v, want %d bytes", test.json, b, test.bytes)
+			}
+		}
+	}
+}
+
+func TestByteCountDoesNotFailMessage(t *testing.T) {
+	var msg struct {
+		Cold  ByteCount `json:"coldBytes"`
+		Other int       `json:"other"`
+	}
+	if err := json.Unmarshal([]byte(`{"coldBytes": "lots", "other": 7}`), &msg); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if msg.Cold.Err == nil || msg.Cold.String() != "lots" || msg.Other != 7 {
+		t.Errorf("got %+v, %d", msg.Cold, msg.Other)
+	}
+}
@@ -0,0 +1,156 @@
+// Package client is a small client for the Code42 REST API, shared by the command line tools in this repository.
+//
+// A Client holds the master server URL, the credentials used for basic authentication and a single pooled
+// http.Client, so every call made by a program reuses the same connections instead of building a new transport
+// per request. Functions in this package return errors instead of exiting; deciding whether an error is fatal is
+// left to the calling program.
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client makes authenticated requests against one Code42 master server. Once its fields are set, a Client may
+// be used by several goroutines at once.
+type Client struct {
+	BaseURL  string // URL of the master server, with port, e.g. https://master.example.com:4285
+	Username string
+	Password string
+
+	Retry RetryPolicy // How failed requests are retried. New sets DefaultRetryPolicy.
+
+	/* Logf, if set, receives a line for each retried attempt and for requests that fail after retrying.
+	The tools set it to log.Printf so the attempts end up in their log file. */
+	Logf func(format string, v ...interface{})
+
+	httpClient *http.Client
+	limiter    *rateLimiter // Set by SetRateLimit
+}
+
+// New returns a Client for the master server at baseURL. Certificate errors are ignored, as Code42 masters
+// commonly run with self-signed certificates.
+func New(baseURL, username, password string) *Client {
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Username:   username,
+		Password:   password,
+		Retry:      DefaultRetryPolicy,
+		httpClient: &http.Client{Transport: tr},
+	}
+}
+
+// NewFromConfigFile reads the master server URL, username and password from the first three lines of the
+// file at path and returns a Client for them.
+func NewFromConfigFile(path string) (*Client, error) {
+	lines, err := ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 3 {
+		return nil, fmt.Errorf("info is missing from config file %s: need server url, username and password", path)
+	}
+
+	/* Trimming extra spaces at beginning and end of lines */
+	return New(strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1]), strings.TrimSpace(lines[2])), nil
+}
+
+// Get performs a GET request on resource (a path plus query string, e.g. "/api/Destination") and returns the
+// response body. If the server answers with a status other than 2xx, the error is an *APIError. Transient
+// failures are retried according to c.Retry.
+func (c *Client) Get(resource string) ([]byte, error) {
+	return c.do("GET", resource, nil)
+}
+
+// Put performs a PUT request on resource with a JSON body and returns the response body. If the server answers
+// with a status other than 2xx, the error is an *APIError. Transient failures are only retried if
+// c.Retry.RetryPUT is set.
+func (c *Client) Put(resource string, body []byte) ([]byte, error) {
+	return c.do("PUT", resource, body)
+}
+
+/* do sends the request, retrying it according to c.Retry */
+func (c *Client) do(method, resource string, body []byte) ([]byte, error) {
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 || (method != "GET" && !c.Retry.RetryPUT) {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		contents, err := c.doOnce(method, resource, body)
+		if err == nil {
+			return contents, nil
+		}
+		if !retryable(err) {
+			return nil, err
+		}
+		if attempt >= attempts {
+			if attempts > 1 {
+				c.logf("%s %s failed after %d attempts: %v", method, resource, attempt, err)
+			}
+			return nil, err
+		}
+
+		wait := c.Retry.delay(attempt+1, err)
+		c.logf("Attempt %d of %d for %s %s failed: %v. Retrying in %v.", attempt, attempts, method, resource, err, wait)
+		time.Sleep(wait)
+	}
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, v...)
+	}
+}
+
+/* doOnce sends a single request and returns the response body, or an *APIError for a non-2xx status */
+func (c *Client) doOnce(method, resource string, body []byte) ([]byte, error) {
+	if c.limiter != nil {
+		c.limiter.wait()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+resource, reqBody)
+	if err != nil {
+		return nil, &RequestError{Method: method, Resource: resource, Err: err}
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making %s request for %s: %v", method, resource, err)
+	}
+	defer resp.Body.Close()
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response for %s %s: %v", method, resource, err)
+	}
+
+	/* A bad password or a server problem must not be mistaken for data */
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(method, resource, resp, contents)
+	}
+
+	return contents, nil
+}
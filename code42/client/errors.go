@@ -0,0 +1,118 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* Kinds of API failure. An *APIError matches one of these with errors.Is, based on its HTTP status code. */
+var (
+	ErrAuth        = errors.New("authentication failed")
+	ErrNotFound    = errors.New("resource not found")
+	ErrRateLimited = errors.New("rate limited by server")
+	ErrServer      = errors.New("server error")
+)
+
+// ErrorDetail is one entry of the error array the Code42 API returns in the body of a failed request.
+type ErrorDetail struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// APIError is returned when the server answers a request with a status code other than 2xx.
+type APIError struct {
+	Method     string
+	Resource   string
+	StatusCode int
+	Errors     []ErrorDetail // Decoded from the response body, if present
+	RetryAfter time.Duration // From the Retry-After header, if present
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.Resource, e.StatusCode, http.StatusText(e.StatusCode))
+	if kind := e.kind(); kind != nil {
+		msg += " (" + kind.Error() + ")"
+	}
+	for _, detail := range e.Errors {
+		msg += "; " + detail.Name
+		if detail.Description != "" {
+			msg += ": " + detail.Description
+		}
+	}
+	return msg
+}
+
+// Is reports whether the error is of the kind given by target, one of ErrAuth, ErrNotFound, ErrRateLimited or ErrServer.
+func (e *APIError) Is(target error) bool {
+	return target != nil && e.kind() == target
+}
+
+func (e *APIError) kind() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrAuth
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// RequestError is returned when a request can't even be built, for example because the server URL in the
+// configuration file is malformed. It is never retried.
+type RequestError struct {
+	Method   string
+	Resource string
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("building %s request for %s: %v", e.Method, e.Resource, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHint returns a line of advice for the usual causes of err, for a tool to print under the error, or "" if
+// there is none.
+func ErrorHint(err error) string {
+	var reqErr *RequestError
+	switch {
+	case errors.Is(err, ErrAuth):
+		return "Check the username and password in the config file."
+	case errors.Is(err, ErrRateLimited):
+		return "The server is limiting requests. Try again later."
+	case errors.As(err, &reqErr):
+		return "Check the master server URL in the config file."
+	}
+	return ""
+}
+
+/* newAPIError builds an APIError from a non-2xx response and its body */
+func newAPIError(method, resource string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{Method: method, Resource: resource, StatusCode: resp.StatusCode}
+
+	/* The error array is normally the whole body, but some resources wrap it in an object */
+	if err := json.Unmarshal(body, &apiErr.Errors); err != nil {
+		wrapped := struct {
+			Errors []ErrorDetail `json:"errors"`
+		}{}
+		if json.Unmarshal(body, &wrapped) == nil {
+			apiErr.Errors = wrapped.Errors
+		}
+	}
+
+	if seconds, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,56 @@
+package client
+
+// Join links users to their devices, and devices to their users, on UserUid. It is built once from the User and
+// DeviceBackupReport resources; each lookup is then a map access instead of a scan of every user or device.
+type Join struct {
+	users   []User                    // In the order given, for UsersWithoutDevices
+	byUser  map[string]User           // By UserUid
+	devices map[string][]DeviceBackup // By UserUid, in the order given
+}
+
+// NewJoin indexes users and devices by UserUid.
+func NewJoin(users []User, devices []DeviceBackup) *Join {
+	j := &Join{
+		users:   users,
+		byUser:  make(map[string]User, len(users)),
+		devices: make(map[string][]DeviceBackup, len(users)),
+	}
+	for _, user := range users {
+		j.byUser[user.UserUid] = user
+	}
+	for _, device := range devices {
+		j.devices[device.UserUid] = append(j.devices[device.UserUid], device)
+	}
+	return j
+}
+
+// DevicesOf returns the devices of the user with the given UserUid.
+func (j *Join) DevicesOf(userUid string) []DeviceBackup {
+	return j.devices[userUid]
+}
+
+// UserOf returns the user a device belongs to, and false if that user was not among the users given to NewJoin.
+func (j *Join) UserOf(device DeviceBackup) (User, bool) {
+	user, ok := j.byUser[device.UserUid]
+	return user, ok
+}
+
+// UsersWithoutDevices returns the users that have no device, in the order they were given to NewJoin.
+func (j *Join) UsersWithoutDevices() []User {
+	var users []User
+	for _, user := range j.users {
+		if len(j.devices[user.UserUid]) == 0 {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// UsersByOrg returns the users of each org, by org name, so that reports can group users and their devices by org.
+func (j *Join) UsersByOrg() map[string][]User {
+	orgs := map[string][]User{}
+	for _, user := range j.users {
+		orgs[user.OrgName] = append(orgs[user.OrgName], user)
+	}
+	return orgs
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	users := []User{
+		{UserUid: "u3", Username: "carol", OrgName: "Sales"},
+		{UserUid: "u1", Username: "alice", OrgName: "Eng"},
+		{UserUid: "u2", Username: "bob", OrgName: "Sales"},
+		{UserUid: "u4", Username: "dave", OrgName: ""},
+	}
+	devices := []DeviceBackup{
+		{DeviceUid: "d2", UserUid: "u1"},
+		{DeviceUid: "d9", UserUid: "ghost"}, // User not in the list, e.g. deactivated
+		{DeviceUid: "d1", UserUid: "u1"},
+		{DeviceUid: "d3", UserUid: "u2"},
+	}
+	join := NewJoin(users, devices)
+
+	deviceUids := func(devices []DeviceBackup) []string {
+		var uids []string
+		for _, device := range devices {
+			uids = append(uids, device.DeviceUid)
+		}
+		return uids
+	}
+	usernames := func(users []User) []string {
+		var names []string
+		for _, user := range users {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+
+	/* Devices keep the order they were given in, not sorted */
+	for userUid, want := range map[string][]string{"u1": {"d2", "d1"}, "u2": {"d3"}, "u3": nil, "ghost": {"d9"}, "nobody": nil} {
+		if got := deviceUids(join.DevicesOf(userUid)); !reflect.DeepEqual(got, want) {
+			t.Errorf("DevicesOf(%q): got %v, want %v", userUid, got, want)
+		}
+	}
+
+	for _, test := range []struct {
+		device DeviceBackup
+		want   string
+		found  bool
+	}{
+		{devices[0], "alice", true},
+		{devices[3], "bob", true},
+		{devices[1], "", false},
+		{DeviceBackup{DeviceUid: "d0"}, "", false},
+	} {
+		if user, found := join.UserOf(test.device); found != test.found || user.Username != test.want {
+			t.Errorf("UserOf(%v): got %q, %v, want %q, %v", test.device.DeviceUid, user.Username, found, test.want, test.found)
+		}
+	}
+
+	/* Users without devices keep the order of the User resource; devices of unknown users don't add any */
+	if got, want := usernames(join.UsersWithoutDevices()), []string{"carol", "dave"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UsersWithoutDevices: got %v, want %v", got, want)
+	}
+
+	orgs := join.UsersByOrg()
+	want := map[string][]string{"Sales": {"carol", "bob"}, "Eng": {"alice"}, "": {"dave"}}
+	if len(orgs) != len(want) {
+		t.Errorf("UsersByOrg: got %d orgs, want %d", len(orgs), len(want))
+	}
+	for org, names := range want {
+		if got := usernames(orgs[org]); !reflect.DeepEqual(got, names) {
+			t.Errorf("UsersByOrg[%q]: got %v, want %v", org, got, names)
+		}
+	}
+}
+
+func TestJoinEmpty(t *testing.T) {
+	join := NewJoin(nil, nil)
+	if len(join.UsersWithoutDevices()) != 0 || len(join.UsersByOrg()) != 0 || len(join.DevicesOf("u1")) != 0 {
+		t.Error("an empty join should have no users or devices")
+	}
+	join = NewJoin(nil, []DeviceBackup{{DeviceUid: "d1", UserUid: "u1"}})
+	if _, found := join.UserOf(DeviceBackup{UserUid: "u1"}); found {
+		t.Error("UserOf: got a user from an empty user list")
+	}
+}
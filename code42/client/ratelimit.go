@@ -0,0 +1,39 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+/* rateLimiter spaces requests evenly so that no more than a fixed number start per second, however many
+goroutines share the Client. */
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // Earliest time the next request may start
+}
+
+/* wait blocks until the caller may start a request */
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(start.Sub(now))
+}
+
+// SetRateLimit caps the number of requests, including retries, that the Client starts per second across all
+// goroutines using it. A value of zero or less removes the cap. It must not be called while requests are in
+// progress.
+func (c *Client) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+		return
+	}
+	c.limiter = &rateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
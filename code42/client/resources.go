@@ -0,0 +1,236 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+/* API resources used by the tools in this repository */
+const (
+	DeviceBackupReportResource = "/api/DeviceBackupReport"
+	ComputerResource           = "/api/Computer"
+	UserResource               = "/api/User"
+	DestinationResource        = "/api/Destination"
+	ColdStorageResource        = "/api/ColdStorage"
+
+	DeviceBackupReportPageSize = 1000 // Page size of 1000 is the default and current max as of 5.1.2.
+	UserPageSize               = 1000 // Newer servers cap pgSize for the User resource, so it is paged too.
+
+	// ArchiveTimeFormat is the layout of dates such as archiveHoldExpireDate returned by the API.
+	ArchiveTimeFormat = "2006-01-02T15:04:05.000-07:00"
+	// ArchiveDateFormat is the layout the ColdStorage resource expects when a purge date is set.
+	ArchiveDateFormat = "2006-01-02"
+)
+
+// DeviceBackup is one row of the DeviceBackupReport resource.
+type DeviceBackup struct {
+	Email                    string `json:"email"`
+	Username                 string `json:"username"`
+	DeviceName               string `json:"deviceName"`
+	Status                   string `json:"status"`
+	LastCompletedBackupDate  string `json:"lastCompletedBackupDate"`
+	LastConnectedDate        string `json:"lastConnectedDate"`
+	BackupCompletePercentage string `json:"backupCompletePercentage"`
+	AlertStates              string `json:"alertStates"`
+	DestinationName          string `json:"destinationName"`
+	OrgName                  string `json:"orgName"`
+	UserUid                  string `json:"userUid"`
+	DeviceUid                string `json:"deviceUid"`
+}
+
+// Computer is the subset of the Computer resource (requested with incAll=true) used by the reports.
+type Computer struct {
+	Guid           string        `json:"guid"`
+	OsName         string        `json:"osName"`
+	OsVersion      string        `json:"osVersion"`
+	ProductVersion string        `json:"productVersion"` // Version of the Code42 app on the device
+	BackupUsage    []BackupUsage `json:"backupUsage"`    // One entry per destination the device backs up to
+}
+
+// BackupUsage is the backup of a device to one destination, as part of the Computer resource.
+type BackupUsage struct {
+	TargetComputerName string `json:"targetComputerName"` // Name of the destination
+	SelectedFiles      int    `json:"selectedFiles"`
+	LastBackup         string `json:"lastBackup"`
+	TodoBytes          int    `json:"todoBytes"`
+	TodoFiles          int    `json:"todoFiles"`
+}
+
+// User is one entry of the User resource.
+type User struct {
+	UserUid  string `json:"userUid"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	OrgUid   string `json:"orgUid"`
+	OrgName  string `json:"orgName"`
+}
+
+// Users is one response of the User resource.
+type Users struct {
+	TotalCount int    `json:"totalCount"`
+	Users      []User `json:"users"`
+}
+
+// Destination is one entry of the Destination resource.
+type Destination struct {
+	DestinationId   int    `json:"destinationId"`
+	Guid            string `json:"guid"`
+	DestinationName string `json:"destinationName"`
+	Type            string `json:"type"`
+	/* coldBytes is a string for PROVIDER destinations but a number for CLUSTER destinations */
+	ColdBytes ByteCount `json:"coldBytes"`
+}
+
+// ColdStorageRow is one archive returned by the ColdStorage resource.
+type ColdStorageRow struct {
+	ArchiveGuid           string `json:"archiveGuid"`
+	ArchiveBytes          int    `json:"archiveBytes"`
+	ArchiveHoldExpireDate string `json:"archiveHoldExpireDate"`
+	SourceComputerName    string `json:"sourceComputerName"`
+	Username              string `json:"username"` // Owner of the device the archive backed up
+	OrgId                 int    `json:"orgId"`
+	OrgName               string `json:"orgName"`
+}
+
+// DeviceBackupReport returns page pgNum (starting at 1) of the DeviceBackupReport resource. If activeOnly is
+// set, deactivated devices are left out. An empty page means there is no more data.
+func (c *Client) DeviceBackupReport(pgNum int, activeOnly bool) ([]DeviceBackup, error) {
+	query := DeviceBackupReportResource + "?pgSize=" + strconv.Itoa(DeviceBackupReportPageSize) + "&pgNum=" + strconv.Itoa(pgNum)
+	if activeOnly {
+		query += "&active=true"
+	}
+
+	msg := struct {
+		Data []DeviceBackup `json:"data"`
+	}{}
+	if err := c.getJSON(query, &msg); err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// Computer returns the Computer resource, with all optional data included, for the device with the given guid.
+func (c *Client) Computer(guid string) (*Computer, error) {
+	msg := struct {
+		Data Computer `json:"data"`
+	}{}
+	if err := c.getJSON(ComputerResource+"/"+guid+"?idType=guid&incAll=true", &msg); err != nil {
+		return nil, err
+	}
+	return &msg.Data, nil
+}
+
+// Users returns page pgNum (starting at 1) of the User resource, pgSize users per page. An empty page means
+// there is no more data. TotalCount is the number of users the server reports in all.
+func (c *Client) Users(pgNum, pgSize int) (*Users, error) {
+	msg := struct {
+		Data Users `json:"data"`
+	}{}
+	if err := c.getJSON(UserResource+"?pgSize="+strconv.Itoa(pgSize)+"&pgNum="+strconv.Itoa(pgNum), &msg); err != nil {
+		return nil, err
+	}
+	return &msg.Data, nil
+}
+
+// AllUsers pages through the User resource and returns every user, with the totalCount the server reported on the
+// first page, so that the caller can check that nothing is missing. The server may return fewer than pgSize users
+// per page, so paging stops at an empty page, once totalCount users have been read, or when a page starts with the
+// same user as the page before it: a server that ignores pgNum would otherwise return the first page forever.
+func (c *Client) AllUsers(pgSize int) ([]User, int, error) {
+	var users []User
+	totalCount := 0
+	previousFirst := ""
+	for page := 1; ; page++ {
+		msg, err := c.Users(page, pgSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		if page == 1 {
+			totalCount = msg.TotalCount
+		}
+		if len(msg.Users) == 0 {
+			return users, totalCount, nil // No more data
+		}
+		if page > 1 && msg.Users[0].UserUid == previousFirst {
+			c.logf("Page %d of %s repeats page %d. Stopping after %d users.", page, UserResource, page-1, len(users))
+			return users, totalCount, nil
+		}
+		previousFirst = msg.Users[0].UserUid
+		users = append(users, msg.Users...)
+		if totalCount > 0 && len(users) >= totalCount {
+			return users, totalCount, nil
+		}
+	}
+}
+
+// Destinations returns every destination known to the master server.
+func (c *Client) Destinations() ([]Destination, error) {
+	msg := struct {
+		Data struct {
+			Destinations []Destination `json:"destinations"`
+		} `json:"data"`
+	}{}
+	if err := c.getJSON(DestinationResource, &msg); err != nil {
+		return nil, err
+	}
+	return msg.Data.Destinations, nil
+}
+
+// ColdStorage returns page pgNum (starting at 1) of the archives in cold storage at the destination with the
+// given id. An empty page means there is no more data.
+func (c *Client) ColdStorage(destinationId, pgNum int) ([]ColdStorageRow, error) {
+	msg := struct {
+		Data struct {
+			ColdStorageRows []ColdStorageRow `json:"coldStorageRows"`
+		} `json:"data"`
+	}{}
+	query := ColdStorageResource + "?destinationId=" + strconv.Itoa(destinationId) + "&pgNum=" + strconv.Itoa(pgNum)
+	if err := c.getJSON(query, &msg); err != nil {
+		return nil, err
+	}
+	return msg.Data.ColdStorageRows, nil
+}
+
+// AllColdStorage pages through the ColdStorage resource and returns every archive in cold storage at the
+// destination with the given id.
+func (c *Client) AllColdStorage(destinationId int) ([]ColdStorageRow, error) {
+	var rows []ColdStorageRow
+	for page := 1; ; page++ {
+		pageRows, err := c.ColdStorage(destinationId, page)
+		if err != nil {
+			return nil, err
+		}
+		if len(pageRows) == 0 {
+			return rows, nil // No more data
+		}
+		rows = append(rows, pageRows...)
+	}
+}
+
+// SetColdStoragePurgeDate changes the archiveHoldExpireDate (aka purge date) of the cold storage archive with
+// the given guid. Only the calendar date of date is sent to the server.
+func (c *Client) SetColdStoragePurgeDate(guid string, date time.Time) error {
+	body := []byte(`{ "archiveHoldExpireDate" : "` + date.Format(ArchiveDateFormat) + `" }`)
+	_, err := c.Put(ColdStorageResource+"/"+guid+"?idType=guid", body)
+	return err
+}
+
+// ClearColdStoragePurgeDate sets the archiveHoldExpireDate of the cold storage archive with the given guid to null.
+func (c *Client) ClearColdStoragePurgeDate(guid string) error {
+	_, err := c.Put(ColdStorageResource+"/"+guid+"?idType=guid", []byte(`{ "archiveHoldExpireDate" : null }`))
+	return err
+}
+
+/* getJSON performs a GET on resource and deserializes the JSON response into v */
+func (c *Client) getJSON(resource string, v interface{}) error {
+	contents, err := c.Get(resource)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(contents, v); err != nil {
+		return fmt.Errorf("unmarshalling JSON from %s: %v", resource, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestAllUsers(t *testing.T) {
+	var all []User
+	for i := 0; i < 8; i++ {
+		all = append(all, User{UserUid: "u" + strconv.Itoa(i), Username: "user" + strconv.Itoa(i)})
+	}
+	tests := []struct {
+		name         string
+		ignorePgNum  bool // Always send the first page
+		totalCount   int
+		wantUsers    int
+		wantRequests int
+	}{
+		{"pages", false, 8, 8, 3},
+		{"no totalCount", false, 0, 8, 4},
+		{"totalCount too high", false, 20, 8, 4},
+		{"pgNum ignored", true, 8, 3, 2},
+		{"pgNum ignored, one page", true, 3, 3, 1},
+	}
+	for _, test := range tests {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			pgNum, _ := strconv.Atoi(r.URL.Query().Get("pgNum"))
+			if test.ignorePgNum || pgNum < 1 {
+				pgNum = 1
+			}
+			pgSize := 3 // Fewer than asked for, as some servers do
+			page := []User{}
+			for i := (pgNum - 1) * pgSize; i < pgNum*pgSize && i < len(all); i++ {
+				page = append(page, all[i])
+			}
+			json.NewEncoder(w).Encode(map[string]Users{"data": {TotalCount: test.totalCount, Users: page}})
+		}))
+
+		c := New(server.URL, "user", "password")
+		users, totalCount, err := c.AllUsers(1000)
+		server.Close()
+		switch {
+		case err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case len(users) != test.wantUsers || totalCount != test.totalCount || requests != test.wantRequests:
+			t.Errorf("%s: got %d users, totalCount %d in %d requests, want %d users, totalCount %d in %d requests",
+				test.name, len(users), totalCount, requests, test.wantUsers, test.totalCount, test.wantRequests)
+		}
+		for i, user := range users {
+			if user.UserUid != all[i].UserUid {
+				t.Errorf("%s: user %d is %v, want %v", test.name, i, user.UserUid, all[i].UserUid)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries requests that fail for transient reasons: a dropped connection,
+// a 5xx response or a 429 rate limit. Other failures, such as a bad password, are never retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts per request, including the first. Less than 2 means no retries.
+	BaseDelay   time.Duration // Wait before the first retry. Doubled for each retry after that.
+	MaxDelay    time.Duration // Upper bound for the wait between attempts, also for a Retry-After. Zero means no bound.
+	RetryPUT    bool          // Also retry PUT requests. GET requests are always retried.
+}
+
+// DefaultRetryPolicy is the policy used by New.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    time.Minute,
+}
+
+/* retryable reports whether a request that failed with err may succeed if it is sent again */
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return errors.Is(apiErr, ErrServer) || errors.Is(apiErr, ErrRateLimited)
+	}
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return false // The request was never sent: a malformed URL stays malformed
+	}
+	return true // No response at all: connection refused, reset, timed out...
+}
+
+// delay returns how long to wait before attempt number attempt (2 for the first retry). The exponential delay
+// is jittered by up to half so that parallel callers do not retry in lockstep. A Retry-After header from the
+// server takes precedence when it asks for a longer wait, up to MaxDelay.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	d := p.BaseDelay
+	for i := 2; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > d {
+		d = apiErr.RetryAfter
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &APIError{StatusCode: 500}, true},
+		{"bad gateway", &APIError{StatusCode: 502}, true},
+		{"rate limited", &APIError{StatusCode: 429}, true},
+		{"bad password", &APIError{StatusCode: 401}, false},
+		{"forbidden", &APIError{StatusCode: 403}, false},
+		{"not found", &APIError{StatusCode: 404}, false},
+		{"bad request", &APIError{StatusCode: 400}, false},
+		{"wrapped server error", fmt.Errorf("listing: %w", &APIError{StatusCode: 503}), true},
+		{"malformed URL", &RequestError{Method: "GET", Resource: "/api/User", Err: errors.New("invalid character")}, false},
+		{"wrapped malformed URL", fmt.Errorf("listing: %w", &RequestError{Err: errors.New("bad")}), false},
+		{"connection refused", errors.New("making GET request for /api/User: connection refused"), true},
+	}
+	for _, test := range tests {
+		if got := retryable(test.err); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	unbounded := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second}
+	retryAfter := func(d time.Duration) error { return &APIError{StatusCode: 429, RetryAfter: d} }
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		{"first retry", policy, 2, nil, 500 * time.Millisecond, time.Second},
+		{"doubled", policy, 3, nil, time.Second, 2 * time.Second},
+		{"doubled again", policy, 5, nil, 4 * time.Second, 8 * time.Second},
+		{"capped", policy, 9, nil, 5 * time.Second, 10 * time.Second},
+		{"longer Retry-After", policy, 2, retryAfter(3 * time.Second), 3 * time.Second, 3 * time.Second},
+		{"shorter Retry-After", policy, 5, retryAfter(time.Second), 4 * time.Second, 8 * time.Second},
+		{"Retry-After above MaxDelay", policy, 2, retryAfter(time.Hour), 10 * time.Second, 10 * time.Second},
+		{"Retry-After without MaxDelay", unbounded, 2, retryAfter(5 * time.Minute), 5 * time.Minute, 5 * time.Minute},
+		{"no delay", RetryPolicy{MaxAttempts: 3}, 2, nil, 0, 0},
+	}
+	for _, test := range tests {
+		for i := 0; i < 50; i++ { // The delay is jittered
+			if got := test.policy.delay(test.attempt, test.err); got < test.min || got > test.max {
+				t.Errorf("%s: got %v, want %v to %v", test.name, got, test.min, test.max)
+				break
+			}
+		}
+	}
+}
+
+func TestMalformedURLIsNotRetried(t *testing.T) {
+	c := New("http://bad host:99", "user", "password")
+	c.Retry = RetryPolicy{MaxAttempts: 4, BaseDelay: time.Hour} // A retry would hang the test
+	attempts := 0
+	c.Logf = func(format string, v ...interface{}) { attempts++ }
+	_, err := c.Get("/api/User")
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("got %v, want a *RequestError", err)
+	}
+	if attempts != 0 {
+		t.Errorf("got %d retries logged, want none", attempts)
+	}
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"bufio"
+	"os"
+	"time"
+)
+
+// ReadLines returns the lines of the file at path.
+func ReadLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// DateStamp returns the current local date as YYYY-MM-DD, used to name log files.
+func DateStamp() string {
+	return time.Now().Format("2006-01-02")
+}
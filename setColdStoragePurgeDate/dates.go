@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* Purge dates are calendar dates: the ColdStorage API is sent YYYY-MM-DD only. Every date the program compares is
+therefore reduced to midnight of its calendar day in one time zone, the one given with -tz. */
+
+/* calendarDate returns midnight, in loc, of the calendar day t falls on in loc */
+func calendarDate(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+/* dateExamples is printed when a date argument can't be read */
+const dateExamples = `Valid dates:
+  TODAY, 10-17-2026 (MM-DD-YYYY), 2026-10-17 (YYYY-MM-DD), 2026-10-17T08:00:00-05:00 (RFC 3339),
+  end-of-month (last day of this month), today-7d, +90d, end-of-month+1m, today+2w.
+  Relative dates count from today, or from end-of-month if it comes first, in days (d), weeks (w), months (m) or years (y).
+  From end-of-month, months and years land on the last day of the month (end-of-month+1m on 09-30 is 10-31)
+  until a day or week offset is added.`
+
+/* relativeDate matches today or end-of-month followed by any number of offsets such as -7d or +3m */
+var relativeDate = regexp.MustCompile(`^(today|end-of-month)?((?:[+-]\d+[dwmy])*)$`)
+var dateOffset = regexp.MustCompile(`([+-]\d+)([dwmy])`)
+
+// parseDate converts a date argument such as -b or -date to a calendar date in loc. It accepts TODAY, MM-DD-YYYY,
+// YYYY-MM-DD, RFC 3339 timestamps and relative dates such as today-7d, +90d and end-of-month; see dateExamples.
+func parseDate(arg string, loc *time.Location) (time.Time, error) {
+	return parseDateAt(arg, loc, time.Now())
+}
+
+/* parseDateAt is parseDate with relative dates counted from now instead of the current time */
+func parseDateAt(arg string, loc *time.Location, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation(shortFormDate, arg, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(client.ArchiveDateFormat, arg, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, arg); err == nil {
+		return calendarDate(t, loc), nil
+	}
+
+	match := relativeDate.FindStringSubmatch(strings.ToLower(strings.TrimSpace(arg)))
+	if match == nil || match[1] == "" && match[2] == "" {
+		return time.Time{}, fmt.Errorf("%q is not a date", arg)
+	}
+	date := calendarDate(now, loc)
+	endOfMonth := match[1] == "end-of-month" // Month and year offsets keep to the last day of the month until a day offset
+	if endOfMonth {
+		date = time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, loc) // Day 0 of next month is the last of this one
+	}
+	for _, offset := range dateOffset.FindAllStringSubmatch(match[2], -1) {
+		n, err := strconv.Atoi(offset[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is not a date: %v", arg, err)
+		}
+		switch offset[2] {
+		case "d":
+			date = date.AddDate(0, 0, n)
+			endOfMonth = false
+		case "w":
+			date = date.AddDate(0, 0, 7*n)
+			endOfMonth = false
+		case "m":
+			date = addMonths(date, n)
+		case "y":
+			date = addMonths(date, 12*n)
+		}
+		if endOfMonth {
+			date = time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, loc)
+		}
+	}
+	return date, nil
+}
+
+/* addMonths adds n months to date, keeping to the last day of the month when the day doesn't exist, e.g. 01-31 + 1m */
+func addMonths(date time.Time, n int) time.Time {
+	y, m, d := date.Date()
+	if last := time.Date(y, m+time.Month(n)+1, 0, 0, 0, 0, 0, date.Location()); d > last.Day() {
+		return last
+	}
+	return time.Date(y, m+time.Month(n), d, 0, 0, 0, 0, date.Location())
+}
+
+/* parseArchiveDate converts an archiveHoldExpireDate returned by the ColdStorage API to a calendar date in loc */
+func parseArchiveDate(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(client.ArchiveTimeFormat, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendarDate(t, loc), nil
+}
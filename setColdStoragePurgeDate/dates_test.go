@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+func TestParseDateAt(t *testing.T) {
+	central := time.FixedZone("CDT", -5*60*60)
+	now := time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		arg  string
+		loc  *time.Location
+		now  time.Time
+		want string // YYYY-MM-DD, or "" for an error
+	}{
+		{arg: "09-30-2026", want: "2026-09-30"},
+		{arg: "2026-09-30", want: "2026-09-30"},
+		{arg: "2026-09-30T23:30:00-05:00", want: "2026-10-01"}, // Already October 1 in UTC
+		{arg: "2026-09-30T23:30:00-05:00", loc: central, want: "2026-09-30"},
+		{arg: "2026-10-01T02:00:00Z", loc: central, want: "2026-09-30"},
+		{arg: "TODAY", want: "2026-09-30"},
+		{arg: "today", now: time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC), want: "2026-10-01"},
+		{arg: "today", loc: central, now: time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC), want: "2026-09-30"},
+		{arg: "today-7d", want: "2026-09-23"},
+		{arg: "+90d", want: "2026-12-29"},
+		{arg: "today+2w", want: "2026-10-14"},
+		{arg: "today+1m", want: "2026-10-30"},
+		{arg: "today+5m", want: "2027-02-28"},
+		{arg: "end-of-month", want: "2026-09-30"},
+		{arg: "end-of-month+1m", want: "2026-10-31"},
+		{arg: "end-of-month+5m", want: "2027-02-28"},
+		{arg: "end-of-month-1y", want: "2025-09-30"},
+		{arg: "end-of-month+1m+1d", want: "2026-11-01"},
+		{arg: "end-of-month+1d+1m", want: "2026-11-01"},
+		{arg: "end-of-month+1m", now: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), want: "2024-02-29"},
+		{arg: "", want: ""},
+		{arg: "tomorrow", want: ""},
+		{arg: "today+5x", want: ""},
+		{arg: "13-01-2026", want: ""},
+	}
+	for _, test := range tests {
+		loc := test.loc
+		if loc == nil {
+			loc = time.UTC
+		}
+		at := test.now
+		if at.IsZero() {
+			at = now
+		}
+		got, err := parseDateAt(test.arg, loc, at)
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("%q in %v: got %v, want an error", test.arg, loc, got)
+		case test.want != "" && err != nil:
+			t.Errorf("%q in %v: unexpected error %v", test.arg, loc, err)
+		case test.want != "" && got.Format(client.ArchiveDateFormat) != test.want:
+			t.Errorf("%q in %v: got %v, want %v", test.arg, loc, got.Format(client.ArchiveDateFormat), test.want)
+		case test.want != "" && (got.Location() != loc || !got.Equal(calendarDate(got, loc))):
+			t.Errorf("%q in %v: got %v, want midnight in %v", test.arg, loc, got, loc)
+		}
+	}
+}
+
+func TestParseArchiveDate(t *testing.T) {
+	central := time.FixedZone("CDT", -5*60*60)
+	tests := []struct {
+		value string
+		loc   *time.Location
+		want  string
+	}{
+		{"2026-10-01T00:00:00.000-05:00", central, "2026-10-01"},
+		{"2026-10-01T00:00:00.000-05:00", time.UTC, "2026-10-01"},
+		{"2026-10-01T02:00:00.000+00:00", central, "2026-09-30"},
+		{"2026-10-01T23:00:00.000-05:00", time.UTC, "2026-10-02"},
+	}
+	for _, test := range tests {
+		got, err := parseArchiveDate(test.value, test.loc)
+		if err != nil || got.Format(client.ArchiveDateFormat) != test.want {
+			t.Errorf("%q in %v: got %v, %v, want %v", test.value, test.loc, got, err, test.want)
+		}
+	}
+	if _, err := parseArchiveDate("2026-10-01", time.UTC); err == nil {
+		t.Error("a date without a time: want an error")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+// listFlag is a command line flag holding a list of values. Values may be separated by commas, and the flag may
+// be given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// archiveFilter narrows the selection down to some destinations, orgs, users and archive sizes. Different kinds
+// of filter must all match; within one list, any value may match. An empty list matches everything.
+type archiveFilter struct {
+	destIds          listFlag
+	excludeDestIds   listFlag
+	destNames        listFlag
+	excludeDestNames listFlag
+	orgs             listFlag // Org names or ids
+	users            listFlag // Usernames
+	minBytes         int64
+	maxBytes         int64 // Zero or less means no maximum
+}
+
+/* matchDestination reports whether archives in dest may be selected */
+func (f *archiveFilter) matchDestination(dest client.Destination) bool {
+	id := strconv.Itoa(dest.DestinationId)
+	if len(f.destIds) > 0 && !contains(f.destIds, id) {
+		return false
+	}
+	if len(f.destNames) > 0 && !contains(f.destNames, dest.DestinationName) {
+		return false
+	}
+	return !contains(f.excludeDestIds, id) && !contains(f.excludeDestNames, dest.DestinationName)
+}
+
+/* matchArchive reports whether an archive of a selected destination may be selected */
+func (f *archiveFilter) matchArchive(row client.ColdStorageRow) bool {
+	if len(f.orgs) > 0 && !contains(f.orgs, row.OrgName) && !contains(f.orgs, strconv.Itoa(row.OrgId)) {
+		return false
+	}
+	if len(f.users) > 0 && !contains(f.users, row.Username) {
+		return false
+	}
+	if int64(row.ArchiveBytes) < f.minBytes {
+		return false
+	}
+	return f.maxBytes <= 0 || int64(row.ArchiveBytes) <= f.maxBytes
+}
+
+/* contains reports whether list holds value, ignoring case */
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
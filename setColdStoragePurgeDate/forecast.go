@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* Values of -forecast: the length of the periods the forecast adds up bytes over */
+const (
+	forecastDay  = "day"
+	forecastWeek = "week"
+	forecastNone = "none"
+)
+
+/* Period of the forecast rows for archives whose old purge date was null or malformed, so never purged */
+const forecastNever = "never"
+
+// forecastPeriod adds up the bytes of the archives purged in one period in one destination, with their old purge
+// dates (before) and with their new ones (after).
+type forecastPeriod struct {
+	destinationId int
+	period        string // Start of the period, YYYY-MM-DD, or forecastNever
+	before        int64
+	after         int64
+}
+
+// purgeForecast returns how many bytes the changes purge per destination and per day or week, before and after the
+// change, sorted by destination and period. Purge dates before today are counted today: the archive is purged at
+// the next purge. Archives not changed purge the same bytes either way, so only the changes are counted.
+func purgeForecast(changes []purgeDateChange, period string, loc *time.Location) []*forecastPeriod {
+	today := calendarDate(time.Now(), loc)
+	start := func(date time.Time) string {
+		if date.Before(today) {
+			date = today
+		}
+		if period == forecastWeek {
+			date = date.AddDate(0, 0, -int(date.Weekday())) // Weeks start on Sunday
+		}
+		return date.Format(client.ArchiveDateFormat)
+	}
+
+	byKey := map[string]*forecastPeriod{}
+	get := func(destId int, period string) *forecastPeriod {
+		key := strconv.Itoa(destId) + " " + period
+		if byKey[key] == nil {
+			byKey[key] = &forecastPeriod{destinationId: destId, period: period}
+		}
+		return byKey[key]
+	}
+	for _, change := range changes {
+		if oldDate, err := parseArchiveDate(change.OldPurgeDate, loc); err == nil {
+			get(change.DestinationId, start(oldDate)).before += change.ArchiveBytes
+		} else {
+			get(change.DestinationId, forecastNever).before += change.ArchiveBytes
+		}
+		if change.NewPurgeDate.IsZero() {
+			get(change.DestinationId, forecastNever).after += change.ArchiveBytes
+		} else {
+			get(change.DestinationId, start(change.NewPurgeDate)).after += change.ArchiveBytes
+		}
+	}
+
+	var forecast []*forecastPeriod
+	for _, p := range byKey {
+		forecast = append(forecast, p)
+	}
+	sort.Slice(forecast, func(i, j int) bool {
+		if forecast[i].destinationId != forecast[j].destinationId {
+			return forecast[i].destinationId < forecast[j].destinationId
+		}
+		return forecast[i].period < forecast[j].period // YYYY-MM-DD sorts by date, and "never" after every date
+	})
+	return forecast
+}
+
+// writeForecast prints the forecast of the bytes the changes purge per destination and per day or week, and writes it
+// to a CSV file with the prefix test_forecast_.
+func writeForecast(changes []purgeDateChange, period string, loc *time.Location) {
+	forecast := purgeForecast(changes, period, loc)
+
+	results := createCSV("test_forecast_", []string{"DestinationId", "Period Start", "Bytes Purged Before", "Bytes Purged After", "Difference"})
+	defer results.close()
+
+	fmt.Printf("Forecast of bytes purged per %s by the archives that would change (before -> after):\n", period)
+	log.Printf("Forecast of bytes purged per %s by the archives that would change (before -> after):\n", period)
+	destId := -1
+	var soonerBytes int64 // Bytes purged earlier than they would have been, in total
+	for _, p := range forecast {
+		if p.destinationId != destId {
+			destId = p.destinationId
+			fmt.Printf("Destination %v:\n", destId)
+		}
+		fmt.Printf("\t%s: %d -> %d bytes (%+d)\n", p.period, p.before, p.after, p.after-p.before)
+		log.Printf("Destination %v, %s: %d -> %d bytes (%+d)\n", destId, p.period, p.before, p.after, p.after-p.before)
+		results.write([]string{strconv.Itoa(destId), p.period, strconv.FormatInt(p.before, 10), strconv.FormatInt(p.after, 10), strconv.FormatInt(p.after-p.before, 10)})
+	}
+	for _, change := range changes {
+		oldDate, err := parseArchiveDate(change.OldPurgeDate, loc)
+		if !change.NewPurgeDate.IsZero() && (err != nil || change.NewPurgeDate.Before(oldDate)) {
+			soonerBytes += change.ArchiveBytes
+		}
+	}
+	fmt.Printf("%d bytes would be purged earlier than with their current purge dates.\n", soonerBytes)
+	log.Printf("%d bytes would be purged earlier than with their current purge dates.\n", soonerBytes)
+	fmt.Println("Forecast written to", results.name)
+}
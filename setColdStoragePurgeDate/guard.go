@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+// guardrails stop a run from scheduling more of cold storage for purge than intended: new purge dates must be at
+// least minDays from today, a run may change at most maxArchives archives, and the changes must be confirmed at a
+// prompt unless yes is set.
+type guardrails struct {
+	minDays     int
+	maxArchives int // 0 means no limit
+	yes         bool
+}
+
+/* checkDate returns an error if date is earlier than minDays from today. A zero date, which sets null, is allowed. */
+func (g guardrails) checkDate(date time.Time, loc *time.Location) error {
+	earliest := calendarDate(time.Now(), loc).AddDate(0, 0, g.minDays)
+	if !date.IsZero() && date.Before(earliest) {
+		return fmt.Errorf("new purge date %s is earlier than %s, %d days from today (-min-days)",
+			date.Format(client.ArchiveDateFormat), earliest.Format(client.ArchiveDateFormat), g.minDays)
+	}
+	return nil
+}
+
+// check returns an error if the changes break a guardrail. checkDates is false for -rollback, which puts back
+// dates that were in place before.
+func (g guardrails) check(changes []purgeDateChange, loc *time.Location, checkDates bool) error {
+	if g.maxArchives > 0 && len(changes) > g.maxArchives {
+		return fmt.Errorf("%d archives would be changed, more than the %d allowed per run (-max-archives)", len(changes), g.maxArchives)
+	}
+	if checkDates {
+		for _, change := range changes {
+			if err := g.checkDate(change.NewPurgeDate, loc); err != nil {
+				return fmt.Errorf("archive %v: %v", change.ArchiveGuid, err)
+			}
+		}
+	}
+	return nil
+}
+
+// confirm prints how many archives, and how many bytes if bytesKnown, each destination would have changed, and
+// asks for "yes" before anything is changed. It returns true without asking if -yes was given or there is nothing
+// to change.
+func (g guardrails) confirm(changes []purgeDateChange, bytesKnown bool) bool {
+	if len(changes) == 0 {
+		return true
+	}
+
+	type destinationTotal struct {
+		archives int
+		bytes    int64
+	}
+	totals := map[int]*destinationTotal{}
+	var destIds []int
+	var totalBytes int64
+	for _, change := range changes {
+		if totals[change.DestinationId] == nil {
+			totals[change.DestinationId] = &destinationTotal{}
+			destIds = append(destIds, change.DestinationId)
+		}
+		totals[change.DestinationId].archives++
+		totals[change.DestinationId].bytes += change.ArchiveBytes
+		totalBytes += change.ArchiveBytes
+	}
+	sort.Ints(destIds)
+
+	fmt.Println("About to change the purge dates of:")
+	for _, destId := range destIds {
+		if bytesKnown {
+			fmt.Printf("\tDestination %v: %d archives, %d bytes\n", destId, totals[destId].archives, totals[destId].bytes)
+		} else {
+			fmt.Printf("\tDestination %v: %d archives\n", destId, totals[destId].archives)
+		}
+	}
+	if bytesKnown {
+		fmt.Printf("\tTotal: %d archives, %d bytes\n", len(changes), totalBytes)
+	} else {
+		fmt.Printf("\tTotal: %d archives\n", len(changes))
+	}
+
+	if g.yes {
+		log.Printf("Change of %d purge dates confirmed with -yes.", len(changes))
+		return true
+	}
+	fmt.Print("Type yes to continue: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(answer)) != "yes" {
+		log.Printf("Change of %d purge dates not confirmed (answer %q).", len(changes), strings.TrimSpace(answer))
+		return false
+	}
+	log.Printf("Change of %d purge dates confirmed at the prompt.", len(changes))
+	return true
+}
+
+/* guard checks the changes against the guardrails and asks for confirmation. It quits if either fails. */
+func (g guardrails) guard(changes []purgeDateChange, loc *time.Location, checkDates, bytesKnown bool) {
+	if err := g.check(changes, loc, checkDates); err != nil {
+		fmt.Println("Refusing to change purge dates:", err)
+		log.Fatalln("Refusing to change purge dates:", err)
+	}
+	if !g.confirm(changes, bytesKnown) {
+		fmt.Println("Not confirmed. Nothing was changed.")
+		log.Fatalln("Not confirmed. Nothing was changed.")
+	}
+}
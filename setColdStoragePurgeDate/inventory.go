@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* Expire Month values of the inventory rows that are not a month */
+const (
+	inventoryBadDate = "null or malformed"
+	inventoryTotal   = "total"
+)
+
+// destinationInventory counts the archives in cold storage of one destination, in total and by the month of their
+// purge date.
+type destinationInventory struct {
+	dest     client.Destination
+	archives int
+	bytes    int64
+	badDates int
+	badBytes int64
+	months   map[string]*monthInventory // By YYYY-MM
+}
+
+type monthInventory struct {
+	archives int
+	bytes    int64
+}
+
+/* add counts one archive in cold storage */
+func (inv *destinationInventory) add(row client.ColdStorageRow, loc *time.Location) {
+	inv.archives++
+	inv.bytes += int64(row.ArchiveBytes)
+
+	purgeDate, err := parseArchiveDate(row.ArchiveHoldExpireDate, loc)
+	if err != nil {
+		log.Printf("Archive %v in destination %v has a null or malformed expiration date %q.", row.ArchiveGuid, inv.dest.DestinationId, row.ArchiveHoldExpireDate)
+		inv.badDates++
+		inv.badBytes += int64(row.ArchiveBytes)
+		return
+	}
+	month := purgeDate.Format("2006-01")
+	if inv.months[month] == nil {
+		inv.months[month] = &monthInventory{}
+	}
+	inv.months[month].archives++
+	inv.months[month].bytes += int64(row.ArchiveBytes)
+}
+
+/* sortedMonths returns the months that have archives, earliest first */
+func (inv *destinationInventory) sortedMonths() []string {
+	var months []string
+	for month := range inv.months {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+	return months
+}
+
+// inventory reports what is in cold storage, without changing anything: for each destination the filters select,
+// the number of archives, their total size and how many expire in each month, on the console and in a CSV file
+// with the prefix inventory_. Null and malformed expiration dates are counted on their own.
+func inventory(c42 *client.Client, filter archiveFilter, loc *time.Location) {
+	fmt.Println("Cold storage inventory. Purge dates are grouped by month in time zone", loc)
+	log.Println("Cold storage inventory. Purge dates are grouped by month in time zone", loc)
+
+	allDestinations, err := c42.Destinations()
+	if err != nil {
+		quitOnAPIError("Error retrieving destinations from Destination API", err)
+	}
+
+	var inventories []*destinationInventory
+	filteredOutCount := 0
+	for _, dest := range allDestinations {
+		if !filter.matchDestination(dest) {
+			log.Printf("Destination %v (%v) left out by the destination filters.", dest.DestinationId, dest.DestinationName)
+			continue
+		}
+		log.Println("Retrieving list of cold storage archives from destination Id:", dest.DestinationId)
+		coldStorageRows, err := c42.AllColdStorage(dest.DestinationId)
+		if err != nil {
+			quitOnAPIError("Error retrieving cold storage archives from ColdStorage API", err)
+		}
+
+		inv := &destinationInventory{dest: dest, months: map[string]*monthInventory{}}
+		for _, coldStorageRow := range coldStorageRows {
+			if !filter.matchArchive(coldStorageRow) {
+				filteredOutCount++
+				continue
+			}
+			inv.add(coldStorageRow, loc)
+		}
+		inventories = append(inventories, inv)
+	}
+
+	results := createCSV("inventory_", []string{"DestinationId", "Destination Name", "Expire Month", "Archives", "Bytes"})
+	defer results.close()
+	row := func(inv *destinationInventory, month string, archives int, bytes int64) {
+		results.write([]string{strconv.Itoa(inv.dest.DestinationId), inv.dest.DestinationName, month, strconv.Itoa(archives), strconv.FormatInt(bytes, 10)})
+	}
+
+	totalArchives, totalBytes, totalBadDates := 0, int64(0), 0
+	for _, inv := range inventories {
+		msg := fmt.Sprintf("Destination %v (%v): %d archives, %d bytes, %d with a null or malformed expiration date.",
+			inv.dest.DestinationId, inv.dest.DestinationName, inv.archives, inv.bytes, inv.badDates)
+		fmt.Println(msg)
+		log.Println(msg)
+		for _, month := range inv.sortedMonths() {
+			m := inv.months[month]
+			fmt.Printf("\t%s: %d archives, %d bytes\n", month, m.archives, m.bytes)
+			row(inv, month, m.archives, m.bytes)
+		}
+		if inv.badDates > 0 {
+			row(inv, inventoryBadDate, inv.badDates, inv.badBytes)
+		}
+		row(inv, inventoryTotal, inv.archives, inv.bytes)
+
+		totalArchives += inv.archives
+		totalBytes += inv.bytes
+		totalBadDates += inv.badDates
+	}
+
+	msg := fmt.Sprintf("Total: %d archives in %d destinations, %d bytes, %d with a null or malformed expiration date.",
+		totalArchives, len(inventories), totalBytes, totalBadDates)
+	fmt.Println(msg)
+	log.Println(msg)
+	if filteredOutCount > 0 {
+		fmt.Printf("%d archives were left out by the org, user and size filters.\n", filteredOutCount)
+		log.Printf("%d archives were left out by the org, user and size filters.\n", filteredOutCount)
+	}
+	fmt.Println("Inventory written to", results.name)
+	log.Println("Inventory written to", results.name)
+}
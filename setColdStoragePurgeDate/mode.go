@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// purgeMode says which archives get the new purge date, given the purge date they have now.
+type purgeMode string
+
+const (
+	modeShorten purgeMode = "shorten" // Only archives purged after the new date: retention gets shorter
+	modeExtend  purgeMode = "extend"  // Only archives purged before the new date: retention gets longer
+	modeSet     purgeMode = "set"     // Every archive, including those with a null or malformed date (-a)
+)
+
+func parseMode(s string) (purgeMode, error) {
+	switch mode := purgeMode(s); mode {
+	case modeShorten, modeExtend, modeSet:
+		return mode, nil
+	}
+	return "", fmt.Errorf("mode %q is not valid: use shorten, extend or set", s)
+}
+
+/* Outcomes of selectArchive */
+type selection int
+
+const (
+	notSelected selection = iota
+	selected
+	atTarget // Already has the new purge date
+	badDate  // Null or malformed purge date, and the mode does not overwrite those
+)
+
+// selectArchive decides whether an archive whose purge date is current (or could not be read, if currentErr is set)
+// gets the new purge date target. Both dates are calendar dates in the same time zone.
+func selectArchive(mode purgeMode, current time.Time, currentErr error, target time.Time) selection {
+	switch {
+	case currentErr == nil && current.Equal(target):
+		return atTarget // Same calendar day as the one we would send. Nothing to do, even in set mode.
+	case mode == modeSet:
+		return selected
+	case currentErr != nil:
+		return badDate
+	case mode == modeShorten && current.After(target):
+		return selected
+	case mode == modeExtend && current.Before(target):
+		return selected
+	}
+	return notSelected
+}
+
+/* Values of the Direction column of the results CSV file */
+const (
+	directionShorten = "shorten"
+	directionExtend  = "extend"
+	directionNone    = "none"  // Already at target
+	directionSet     = "set"   // Old purge date was null or malformed
+	directionClear   = "clear" // New purge date is null (rollback)
+)
+
+// direction tells whether a change moves the purge date earlier or later. Dates are compared as calendar dates in the
+// time zone of the new purge date.
+func (c purgeDateChange) direction() string {
+	if c.NewPurgeDate.IsZero() {
+		return directionClear
+	}
+	old, err := parseArchiveDate(c.OldPurgeDate, c.NewPurgeDate.Location())
+	switch {
+	case err != nil:
+		return directionSet
+	case old.After(c.NewPurgeDate):
+		return directionShorten
+	case old.Before(c.NewPurgeDate):
+		return directionExtend
+	}
+	return directionNone
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSelectArchive(t *testing.T) {
+	target := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	earlier := target.AddDate(0, 0, -1)
+	later := target.AddDate(0, 0, 1)
+	malformed := errors.New("malformed")
+	tests := []struct {
+		mode       purgeMode
+		current    time.Time
+		currentErr error
+		want       selection
+	}{
+		{modeShorten, later, nil, selected},
+		{modeShorten, earlier, nil, notSelected},
+		{modeShorten, target, nil, atTarget},
+		{modeShorten, time.Time{}, malformed, badDate},
+		{modeExtend, earlier, nil, selected},
+		{modeExtend, later, nil, notSelected},
+		{modeExtend, target, nil, atTarget},
+		{modeExtend, time.Time{}, malformed, badDate},
+		{modeSet, later, nil, selected},
+		{modeSet, earlier, nil, selected},
+		{modeSet, target, nil, atTarget},
+		{modeSet, time.Time{}, malformed, selected},
+	}
+	for _, test := range tests {
+		if got := selectArchive(test.mode, test.current, test.currentErr, target); got != test.want {
+			t.Errorf("%s, current %v, error %v: got %v, want %v", test.mode, test.current.Format("2006-01-02"), test.currentErr, got, test.want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"shorten", "extend", "set"} {
+		if mode, err := parseMode(s); err != nil || string(mode) != s {
+			t.Errorf("%q: got %q, %v", s, mode, err)
+		}
+	}
+	for _, s := range []string{"", "Shorten", "all"} {
+		if _, err := parseMode(s); err == nil {
+			t.Errorf("%q: want an error", s)
+		}
+	}
+}
+
+func TestDirection(t *testing.T) {
+	central := time.FixedZone("CDT", -5*60*60)
+	newDate := time.Date(2026, 10, 17, 0, 0, 0, 0, central)
+	tests := []struct {
+		old     string
+		newDate time.Time
+		want    string
+	}{
+		{"2026-11-01T00:00:00.000-05:00", newDate, directionShorten},
+		{"2026-10-01T00:00:00.000-05:00", newDate, directionExtend},
+		{"2026-10-17T00:00:00.000-05:00", newDate, directionNone},
+		{"2026-10-17T04:00:00.000+00:00", newDate, directionExtend}, // Still October 16 in the time zone of the new date
+		{"", newDate, directionSet},
+		{"2026-10-17", newDate, directionSet},
+		{"2026-10-17T00:00:00.000-05:00", time.Time{}, directionClear},
+	}
+	for _, test := range tests {
+		change := purgeDateChange{OldPurgeDate: test.old, NewPurgeDate: test.newDate}
+		if got := change.direction(); got != test.want {
+			t.Errorf("%q to %v: got %v, want %v", test.old, test.newDate, got, test.want)
+		}
+	}
+}
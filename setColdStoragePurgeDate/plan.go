@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* purgePlan is the file written by -plan and executed by -apply. It records exactly which archives a run selected
+and what their purge dates were, so the change can be approved before it is made. */
+type purgePlan struct {
+	Created  time.Time         `json:"created"`
+	Host     string            `json:"host"`     // Master server the plan was made against
+	TimeZone string            `json:"timeZone"` // -tz of the run that made the plan
+	Criteria map[string]string `json:"criteria"` // Every command line flag of that run, with its value
+	Archives []plannedArchive  `json:"archives"`
+}
+
+type plannedArchive struct {
+	ArchiveGuid   string `json:"archiveGuid"`
+	DestinationId int    `json:"destinationId"`
+	OldPurgeDate  string `json:"oldPurgeDate"` // archiveHoldExpireDate when the plan was made, as returned by the API
+	NewPurgeDate  string `json:"newPurgeDate"` // YYYY-MM-DD
+}
+
+/* writePlan saves the selected changes, and the flags that selected them, to a plan file at path */
+func writePlan(path, host string, loc *time.Location, changes []purgeDateChange) {
+	plan := purgePlan{
+		Created:  time.Now(),
+		Host:     host,
+		TimeZone: loc.String(),
+		Criteria: commandLineFlags(),
+	}
+	for _, change := range changes {
+		plan.Archives = append(plan.Archives, plannedArchive{
+			ArchiveGuid:   change.ArchiveGuid,
+			DestinationId: change.DestinationId,
+			OldPurgeDate:  change.OldPurgeDate,
+			NewPurgeDate:  change.NewPurgeDate.Format(client.ArchiveDateFormat),
+		})
+	}
+
+	contents, err := json.MarshalIndent(plan, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(path, contents, 0666)
+	}
+	if err != nil {
+		fmt.Println("Can't write plan file:", err)
+		log.Fatalf("Can't write plan file %v: %v", path, err)
+	}
+
+	fmt.Printf("Plan with %d archives written to %s. Nothing was changed. Run with -apply %s to make the changes.\n", len(plan.Archives), path, path)
+	log.Printf("Plan with %d archives written to %s.\n", len(plan.Archives), path)
+}
+
+/* applyPlan makes the changes recorded in the plan file at path. An archive is only changed if its purge date is
+still the one recorded in the plan; otherwise it is left alone and reported. */
+func applyPlan(c42 *client.Client, path string, workers int, guard guardrails) {
+	fmt.Println("Applying plan", path)
+	log.Println("Applying plan", path)
+
+	plan := purgePlan{}
+	contents, err := ioutil.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(contents, &plan)
+	}
+	if err != nil {
+		fmt.Println("Can't read plan file:", err)
+		log.Fatalf("Can't read plan file %v: %v", path, err)
+	}
+	if plan.Host != c42.BaseURL {
+		fmt.Printf("The plan was made against %s, not %s. Quitting.\n", plan.Host, c42.BaseURL)
+		log.Fatalf("The plan was made against %s, not %s. Quitting.", plan.Host, c42.BaseURL)
+	}
+	loc, err := time.LoadLocation(plan.TimeZone)
+	if err != nil {
+		log.Fatalf("Time zone %q of the plan is not valid: %v", plan.TimeZone, err)
+	}
+	log.Printf("Plan created %v with %d archives. Criteria: %v", plan.Created, len(plan.Archives), plan.Criteria)
+
+	/* Re-read the current purge date of every archive in the destinations the plan touches */
+	currentRows := map[string]client.ColdStorageRow{} // By archive GUID
+	destinationsRead := map[int]bool{}
+	for _, archive := range plan.Archives {
+		if destinationsRead[archive.DestinationId] {
+			continue
+		}
+		destinationsRead[archive.DestinationId] = true
+		rows, err := c42.AllColdStorage(archive.DestinationId)
+		if err != nil {
+			quitOnAPIError("Error retrieving cold storage archives from ColdStorage API", err)
+		}
+		for _, row := range rows {
+			currentRows[row.ArchiveGuid] = row
+		}
+	}
+
+	var skipped [][]string // Results rows of the archives left alone
+	goneCount, atTargetCount, changedCount := 0, 0, 0 // Archives left alone, by reason
+	var changes []purgeDateChange
+	for _, archive := range plan.Archives {
+		newPurgeDate, err := time.ParseInLocation(client.ArchiveDateFormat, archive.NewPurgeDate, loc)
+		if err != nil {
+			log.Fatalf("Plan file has a bad new purge date %q for archive %v", archive.NewPurgeDate, archive.ArchiveGuid)
+		}
+		change := purgeDateChange{ArchiveGuid: archive.ArchiveGuid, OldPurgeDate: archive.OldPurgeDate, NewPurgeDate: newPurgeDate, DestinationId: archive.DestinationId}
+
+		currentRow, found := currentRows[archive.ArchiveGuid]
+		current := currentRow.ArchiveHoldExpireDate
+		change.ArchiveBytes = int64(currentRow.ArchiveBytes)
+		switch {
+		case !found:
+			log.Printf("Archive %v is no longer in cold storage. Skipping.", archive.ArchiveGuid)
+			skipped = append(skipped, change.record(statusNotInColdStorage))
+			goneCount++
+		case current != archive.OldPurgeDate:
+			if currentDate, err := parseArchiveDate(current, loc); err == nil && currentDate.Equal(newPurgeDate) {
+				skipped = append(skipped, change.record(statusAtTarget))
+				atTargetCount++
+				continue
+			}
+			log.Printf("Purge date of archive %v changed since the plan was made: %q, planned %q. Skipping.", archive.ArchiveGuid, current, archive.OldPurgeDate)
+			change.OldPurgeDate = current
+			skipped = append(skipped, change.record(statusChangedSincePlan))
+			changedCount++
+		default:
+			changes = append(changes, change)
+		}
+	}
+	msg := fmt.Sprintf("%d archives still match the plan. Skipped: %d whose purge date changed since the plan was made, %d already at the new purge date, %d no longer in cold storage.",
+		len(changes), changedCount, atTargetCount, goneCount)
+	fmt.Println(msg, "See the results file.")
+	log.Println(msg)
+
+	/* The plan may be old: check its dates against -min-days as of today */
+	guard.guard(changes, loc, true, true)
+
+	results := createResults("apply_")
+	defer results.close()
+	for _, record := range skipped {
+		results.write(record)
+	}
+
+	totalCount, failedCount, _ := applyChanges(c42, changes, workers, results)
+
+	fmt.Println("Total number of purge dates changed:", totalCount)
+	log.Println("Total number of purge dates changed:", totalCount)
+	if failedCount > 0 {
+		fmt.Printf("%d purge date changes failed. See log for archive GUIDs and errors.\n", failedCount)
+		log.Printf("%d purge date changes failed.\n", failedCount)
+	}
+
+	fmt.Println("Results written to", results.name)
+	fmt.Println("Done.")
+	log.Println("Done.")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+// retentionPolicy is a policy file given with -policy. Each archive gets the purge date of the first rule that
+// matches it; archives no rule matches are left alone.
+//
+// Example:
+//
+//	{ "rules": [
+//	    { "name": "Legal hold", "org": ["Legal"], "days": 3650, "mode": "extend" },
+//	    { "name": "Cloud", "destinationName": ["CrashPlan Central"], "days": 90 },
+//	    { "name": "Everything else", "days": 180 }
+//	] }
+type retentionPolicy struct {
+	Rules []*retentionRule `json:"rules"`
+}
+
+// retentionRule matches archives by destination and org. A rule without any match field matches every archive.
+// Within one field, any value may match; all fields given must match.
+type retentionRule struct {
+	Name             string   `json:"name"`
+	DestinationIds   []int    `json:"destinationId"`
+	DestinationNames []string `json:"destinationName"`
+	Orgs             []string `json:"org"`  // Org names or ids
+	Days             int      `json:"days"` // Purge date is this many days after the baseline date (-b)
+	Mode             string   `json:"mode"` // shorten (the default), extend or set
+
+	mode purgeMode
+
+	/* Counts for the summary */
+	changed   int
+	atTarget  int
+	badDate   int
+	unchanged int
+}
+
+/* readPolicy reads and checks the policy file at path */
+func readPolicy(path string) (*retentionPolicy, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	/* A misspelled field would otherwise be ignored, and could turn a rule into one that matches every archive */
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+	policy := &retentionPolicy{}
+	if err := decoder.Decode(policy); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(policy.Rules) == 0 {
+		return nil, fmt.Errorf("%s has no rules", path)
+	}
+
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			rule.Name = "rule " + strconv.Itoa(i+1)
+		}
+		if rule.Days < 0 {
+			return nil, fmt.Errorf("%s: %s: days must be greater than or equal to zero", path, rule.Name)
+		}
+		if rule.Mode == "" {
+			rule.Mode = string(modeShorten)
+		}
+		if rule.mode, err = parseMode(rule.Mode); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, rule.Name, err)
+		}
+	}
+	return policy, nil
+}
+
+/* match returns the first rule matching an archive of dest, or nil */
+func (p *retentionPolicy) match(dest client.Destination, row client.ColdStorageRow) *retentionRule {
+	for _, rule := range p.Rules {
+		if rule.matches(dest, row) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *retentionRule) matches(dest client.Destination, row client.ColdStorageRow) bool {
+	if len(r.DestinationIds) > 0 {
+		found := false
+		for _, id := range r.DestinationIds {
+			found = found || id == dest.DestinationId
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.DestinationNames) > 0 && !contains(r.DestinationNames, dest.DestinationName) {
+		return false
+	}
+	return len(r.Orgs) == 0 || contains(r.Orgs, row.OrgName) || contains(r.Orgs, strconv.Itoa(row.OrgId))
+}
+
+/* count records the outcome of selectArchive for an archive this rule matched */
+func (r *retentionRule) count(s selection) {
+	switch s {
+	case selected:
+		r.changed++
+	case atTarget:
+		r.atTarget++
+	case badDate:
+		r.badDate++
+	default:
+		r.unchanged++
+	}
+}
+
+/* logRules prints and logs the purge date each rule sets */
+func (p *retentionPolicy) logRules(baseLineDate time.Time) {
+	for _, rule := range p.Rules {
+		msg := fmt.Sprintf("Rule %q: %s to %s (%d days after baseline)", rule.Name, rule.mode, baseLineDate.AddDate(0, 0, rule.Days).Format(client.ArchiveDateFormat), rule.Days)
+		fmt.Println(msg)
+		log.Println(msg)
+	}
+}
+
+/* printSummary prints and logs how many archives each rule touched */
+func (p *retentionPolicy) printSummary(unmatched int, testOnly bool) {
+	verb := "selected for change"
+	if testOnly {
+		verb = "would change"
+	}
+	for _, rule := range p.Rules {
+		msg := fmt.Sprintf("Rule %q: %d archives %s, %d already at target, %d left alone, %d with a null or malformed date.",
+			rule.Name, rule.changed, verb, rule.atTarget, rule.unchanged, rule.badDate)
+		fmt.Println(msg)
+		log.Println(msg)
+	}
+	fmt.Printf("%d archives matched no rule and were left alone.\n", unmatched)
+	log.Printf("%d archives matched no rule and were left alone.\n", unmatched)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* resultsFile is a results CSV file written one row at a time. Each row is flushed to disk as soon as it is
+written, so if the program is killed the file still lists every archive processed up to that point. */
+type resultsFile struct {
+	name string
+	file *os.File
+	w    *csv.Writer
+}
+
+/* createResults creates a date-stamped results CSV file whose name starts with prefix, and writes its header */
+func createResults(prefix string) *resultsFile {
+	return createCSV(prefix+"results_", []string{"Archive GUID", "Old Purge Date", "New Purge Date", "DestinationId", "Status", "Direction"})
+}
+
+/* createCSV creates a date-stamped CSV file whose name starts with prefix, and writes header */
+func createCSV(prefix string, header []string) *resultsFile {
+	name := prefix + strings.Replace(time.Now().Format(time.Stamp), " ", "_", -1) + ".csv"
+	file, err := os.Create(name)
+	if err != nil {
+		log.Fatalln("Error creating CSV file:", err)
+	}
+	r := &resultsFile{name: name, file: file, w: csv.NewWriter(file)}
+	r.write(header)
+	return r
+}
+
+/* appendResults reopens an existing results CSV file to add rows to it */
+func appendResults(name string) *resultsFile {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		log.Fatalln("Error opening CSV file:", err)
+	}
+	return &resultsFile{name: name, file: file, w: csv.NewWriter(file)}
+}
+
+func (r *resultsFile) write(record []string) {
+	r.w.Write(record)
+	r.w.Flush()
+	if err := r.w.Error(); err != nil {
+		log.Fatalln("Error writing csv:", err)
+	}
+}
+
+func (r *resultsFile) close() {
+	r.file.Close()
+}
+
+/* checkpointFile is where a run that changes purge dates records its progress, so that -resume can continue it */
+const checkpointFile = "setColdStoragePurgeDate.checkpoint"
+
+/* checkpoint points -resume to the results file of an interrupted run. The results file itself records which
+archives were changed. The checkpoint is removed when a run finishes without failures. */
+type checkpoint struct {
+	ResultsFile string            `json:"resultsFile"`
+	Started     time.Time         `json:"started"`
+	Criteria    map[string]string `json:"criteria"` // Command line flags of the run
+	Targets     []string          `json:"targets"`  // New purge dates of the run, from purgeTargets
+}
+
+/* purgeTargets lists the new purge dates of a run: the one new purge date, or the date of each policy rule */
+func purgeTargets(newPurgeDate, baseLineDate time.Time, policy *retentionPolicy) []string {
+	if policy == nil {
+		return []string{newPurgeDate.Format(client.ArchiveDateFormat)}
+	}
+	var targets []string
+	for _, rule := range policy.Rules {
+		targets = append(targets, rule.Name+": "+baseLineDate.AddDate(0, 0, rule.Days).Format(client.ArchiveDateFormat))
+	}
+	return targets
+}
+
+/* Flags that may differ between a run and its -resume without changing which archives are selected */
+var resumableFlags = map[string]bool{
+	"resume": true, "workers": true, "rps": true, "retries": true, "retrywait": true, "retryput": true,
+	"yes": true, "min-days": true, "max-archives": true, // Guardrails, checked again on resume
+}
+
+/* writeCheckpoint records that the run started at started, writing to results, is in progress */
+func writeCheckpoint(results *resultsFile, started time.Time, targets []string) {
+	cp := checkpoint{ResultsFile: results.name, Started: started, Criteria: commandLineFlags(), Targets: targets}
+	contents, err := json.MarshalIndent(cp, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(checkpointFile, contents, 0666)
+	}
+	if err != nil {
+		log.Fatalln("Error writing checkpoint file:", err)
+	}
+}
+
+/* readCheckpoint returns the checkpoint of an interrupted run. It refuses a checkpoint made with other selection flags,
+or with other new purge dates: a relative -b such as TODAY gives other dates on another day. */
+func readCheckpoint(targets []string) (*checkpoint, error) {
+	contents, err := ioutil.ReadFile(checkpointFile)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(contents, cp); err != nil {
+		return nil, fmt.Errorf("%s: %v", checkpointFile, err)
+	}
+
+	var differences []string
+	for name, value := range commandLineFlags() {
+		if !resumableFlags[name] && cp.Criteria[name] != value {
+			differences = append(differences, fmt.Sprintf("-%s is %q, was %q", name, value, cp.Criteria[name]))
+		}
+	}
+	if len(differences) > 0 {
+		return nil, fmt.Errorf("the interrupted run used other arguments: %s", strings.Join(differences, "; "))
+	}
+	if strings.Join(cp.Targets, ", ") != strings.Join(targets, ", ") {
+		return nil, fmt.Errorf("the new purge dates are now %s, but the interrupted run used %s", strings.Join(targets, ", "), strings.Join(cp.Targets, ", "))
+	}
+	return cp, nil
+}
+
+/* removeCheckpoint is called when a run is complete */
+func removeCheckpoint() {
+	if err := os.Remove(checkpointFile); err != nil && !os.IsNotExist(err) {
+		log.Println("Error removing checkpoint file:", err)
+	}
+}
+
+/* commandLineFlags returns every command line flag of this run, with its value */
+func commandLineFlags() map[string]string {
+	flags := map[string]string{}
+	flag.VisitAll(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCheckpointTargets(t *testing.T) {
+	started := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		written []string
+		now     []string
+		wantErr bool
+	}{
+		{"same date", []string{"2026-11-15"}, []string{"2026-11-15"}, false},
+		{"same rules", []string{"Legal: 2036-10-14", "Cloud: 2027-01-14"}, []string{"Legal: 2036-10-14", "Cloud: 2027-01-14"}, false},
+		{"-b TODAY on the next day", []string{"2026-11-15"}, []string{"2026-11-16"}, true},
+		{"rule date moved", []string{"Legal: 2036-10-14", "Cloud: 2027-01-14"}, []string{"Legal: 2036-10-14", "Cloud: 2027-01-15"}, true},
+		{"rule added", []string{"Cloud: 2027-01-14"}, []string{"Cloud: 2027-01-14", "Everything else: 2027-04-14"}, true},
+		{"policy instead of date", []string{"2026-11-15"}, []string{"rule 1: 2026-11-15"}, true},
+		{"no targets recorded", nil, []string{"2026-11-15"}, true},
+	}
+	t.Chdir(t.TempDir())
+	for _, test := range tests {
+		writeCheckpoint(&resultsFile{name: "results_Oct_16_09:30:00.csv"}, started, test.written)
+		cp, err := readCheckpoint(test.now)
+		switch {
+		case test.wantErr && err == nil:
+			t.Errorf("%s: got no error, want one", test.name)
+		case test.wantErr && !strings.Contains(err.Error(), "new purge dates"):
+			t.Errorf("%s: got %v, want an error about the new purge dates", test.name, err)
+		case !test.wantErr && err != nil:
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		case !test.wantErr && (cp.ResultsFile != "results_Oct_16_09:30:00.csv" || !cp.Started.Equal(started)):
+			t.Errorf("%s: got %+v, want the results file and start time of the interrupted run", test.name, cp)
+		}
+		removeCheckpoint()
+	}
+}
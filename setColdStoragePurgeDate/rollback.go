@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* rollback puts back the Old Purge Date of every archive listed as changed in the results CSV file at path, and
+writes its own results file with the prefix rollback_. Rows whose old date was null or malformed, and files
+without a Status column, are refused unless force is set. Results files of test runs are always refused. */
+func rollback(c42 *client.Client, path string, loc *time.Location, force bool, workers int, guard guardrails) {
+	fmt.Println("Rolling back the purge date changes listed in", path)
+	log.Println("Rolling back the purge date changes listed in", path)
+
+	/* A test run changed nothing, so there is nothing to roll back */
+	if strings.HasPrefix(filepath.Base(path), "test_") {
+		fmt.Println(path, "is the results file of a test run (-t), which changed nothing. Refusing to roll it back.")
+		log.Fatalf("%v is the results file of a test run. Refusing to roll it back.", path)
+	}
+
+	rows, err := readResults(path)
+	if err != nil {
+		fmt.Println("Can't read results file:", err)
+		log.Fatalf("Can't read results file %v: %v", path, err)
+	}
+
+	/* Results files written before the Status column was added don't say whether they come from a test run */
+	if len(rows) > 0 && rows[0].status == "" {
+		if !force {
+			fmt.Println(path, "has no Status column, so it may be from a test run that changed nothing. Use -force to roll back every row in it anyway.")
+			log.Fatalf("%v has no Status column. Refusing to roll it back without -force.", path)
+		}
+		log.Printf("%v has no Status column: with -force, every row in it is rolled back as changed.", path)
+		for i := range rows {
+			rows[i].status = statusChanged
+		}
+	}
+
+	restores, refused := restoresFor(rows, loc, force)
+	fmt.Printf("%d archives to roll back, %d refused because their old purge date was null or malformed.\n", len(restores), len(refused))
+	log.Printf("%d archives to roll back, %d refused because their old purge date was null or malformed.\n", len(restores), len(refused))
+
+	/* The dates put back were in place before, so -min-days does not apply; the ColdStorage rows were not read, so sizes are unknown */
+	guard.guard(restores, loc, false, false)
+
+	results := createResults("rollback_")
+	defer results.close()
+	for _, change := range refused {
+		results.write(change.record(statusRefused))
+	}
+	totalCount, failedCount, _ := applyChanges(c42, restores, workers, results)
+
+	fmt.Println("Total number of purge dates rolled back:", totalCount)
+	log.Println("Total number of purge dates rolled back:", totalCount)
+	if failedCount > 0 {
+		fmt.Printf("%d rollbacks failed. See log for archive GUIDs and errors.\n", failedCount)
+		log.Printf("%d rollbacks failed.\n", failedCount)
+	}
+
+	fmt.Println("Rollback results written to", results.name)
+	fmt.Println("Done.")
+	log.Println("Done.")
+}
+
+/* restoresFor returns the changes that put back the old purge dates of the rows listed as changed, and those
+refused. The "old" date of a restore is the date the earlier run set; the "new" one is the date it replaced. */
+func restoresFor(rows []resultRow, loc *time.Location, force bool) ([]purgeDateChange, []purgeDateChange) {
+	var restores []purgeDateChange
+	var refused []purgeDateChange
+	for _, row := range rows {
+		if row.status != statusChanged {
+			continue // Test runs and archives already at target changed nothing
+		}
+
+		restore := purgeDateChange{ArchiveGuid: row.archiveGuid, OldPurgeDate: row.newPurgeDate, DestinationId: row.destinationId}
+		oldDate, err := recordedDate(row.oldPurgeDate, loc)
+		switch {
+		case err == nil:
+			restore.NewPurgeDate = oldDate
+		case force && row.oldPurgeDate == "":
+			/* NewPurgeDate stays zero: the archive gets a null date back */
+		case force && len(row.oldPurgeDate) >= 10:
+			if oldDate, err := time.ParseInLocation(client.ArchiveDateFormat, row.oldPurgeDate[:10], loc); err == nil {
+				restore.NewPurgeDate = oldDate
+			} else {
+				log.Printf("Refusing to roll back archive %v: old purge date %q can't be read, even with -force", row.archiveGuid, row.oldPurgeDate)
+				refused = append(refused, restore)
+				continue
+			}
+		default:
+			log.Printf("Refusing to roll back archive %v: old purge date %q is null or malformed. Use -force to restore it anyway.", row.archiveGuid, row.oldPurgeDate)
+			refused = append(refused, restore)
+			continue
+		}
+		restores = append(restores, restore)
+	}
+	return restores, refused
+}
+
+/* recordedDate returns the calendar date of an Old Purge Date in its own offset, which is the date the server had
+stored, as midnight in loc. Converting it to loc first could put back the day before or after. */
+func recordedDate(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(client.ArchiveTimeFormat, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc), nil
+}
+
+/* resultRow is one row of a results CSV file written by this program */
+type resultRow struct {
+	archiveGuid   string
+	oldPurgeDate  string
+	newPurgeDate  string
+	destinationId int
+	status        string
+}
+
+/* readResults reads a results CSV file. Files written before the Status column was added have four columns; their
+rows have an empty status. */
+func readResults(path string) ([]resultRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || len(records[0]) < 4 || records[0][0] != "Archive GUID" {
+		return nil, fmt.Errorf("%s is not a results file: header row is missing", path)
+	}
+
+	var rows []resultRow
+	for i, record := range records[1:] {
+		if len(record) < 4 {
+			return nil, fmt.Errorf("%s line %d: expected at least 4 fields, got %d", path, i+2, len(record))
+		}
+		destinationId, err := strconv.Atoi(strings.TrimSpace(record[3]))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: bad DestinationId %q", path, i+2, record[3])
+		}
+		row := resultRow{archiveGuid: record[0], oldPurgeDate: strings.TrimSpace(record[1]), newPurgeDate: record[2], destinationId: destinationId}
+		if len(record) > 4 {
+			row.status = record[4]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+func TestRestoresFor(t *testing.T) {
+	chicago := time.FixedZone("CST", -6*60*60) // -tz of a laptop, while the server stores dates in UTC
+	tokyo := time.FixedZone("JST", 9*60*60)
+	changed := func(old string) resultRow {
+		return resultRow{archiveGuid: "A", oldPurgeDate: old, newPurgeDate: "2026-10-20T00:00:00.000+00:00", destinationId: 1, status: statusChanged}
+	}
+	tests := []struct {
+		row     resultRow
+		loc     *time.Location
+		force   bool
+		want    string // Date put back, "null", or "" if refused
+		skipped bool   // Not a change, so not rolled back at all
+	}{
+		{row: changed("2027-01-01T00:00:00.000+00:00"), loc: chicago, want: "2027-01-01"},
+		{row: changed("2027-01-01T00:00:00.000+00:00"), loc: tokyo, want: "2027-01-01"},
+		{row: changed("2027-01-01T23:00:00.000-06:00"), loc: time.UTC, want: "2027-01-01"},
+		{row: changed("2027-01-01T00:00:00.000+00:00"), loc: time.UTC, want: "2027-01-01"},
+		{row: changed(""), loc: chicago, want: ""},
+		{row: changed(""), loc: chicago, force: true, want: "null"},
+		{row: changed("2027-01-01 garbage"), loc: chicago, want: ""},
+		{row: changed("2027-01-01 garbage"), loc: chicago, force: true, want: "2027-01-01"},
+		{row: changed("garbage"), loc: chicago, force: true, want: ""},
+		{row: resultRow{archiveGuid: "A", oldPurgeDate: "2027-01-01T00:00:00.000+00:00", status: statusAtTarget}, loc: chicago, skipped: true},
+		{row: resultRow{archiveGuid: "A", oldPurgeDate: "2027-01-01T00:00:00.000+00:00", status: statusWouldChange}, loc: chicago, skipped: true},
+	}
+	for _, test := range tests {
+		restores, refused := restoresFor([]resultRow{test.row}, test.loc, test.force)
+		switch {
+		case test.skipped:
+			if len(restores)+len(refused) != 0 {
+				t.Errorf("%+v: got %v, %v, want nothing", test.row, restores, refused)
+			}
+		case test.want == "":
+			if len(refused) != 1 || len(restores) != 0 {
+				t.Errorf("%+v force %v: got %v, want refused", test.row, test.force, restores)
+			}
+		case len(restores) != 1:
+			t.Errorf("%+v force %v in %v: refused, want %v", test.row, test.force, test.loc, test.want)
+		case test.want == "null":
+			if !restores[0].NewPurgeDate.IsZero() {
+				t.Errorf("%+v: got %v, want null", test.row, restores[0].NewPurgeDate)
+			}
+		default:
+			got := restores[0]
+			if got.NewPurgeDate.Format(client.ArchiveDateFormat) != test.want || got.NewPurgeDate.Location() != test.loc {
+				t.Errorf("%q in %v: got %v, want %v", test.row.oldPurgeDate, test.loc, got.NewPurgeDate, test.want)
+			}
+			if got.OldPurgeDate != test.row.newPurgeDate || got.DestinationId != test.row.destinationId {
+				t.Errorf("%+v: got %+v", test.row, got)
+			}
+		}
+	}
+}
@@ -2,14 +2,81 @@
 two parameters: a baseline date and the number of days, N, after the baseline date to set the purge date to.
 
 The program accepts the following command line parameters:
-1. [-b date ] Baseline date . Date format: either TODAY or MM-DD-YYYY
+1. [-b date ] Baseline date . Date format: TODAY, MM-DD-YYYY, YYYY-MM-DD, an RFC 3339 timestamp such as
+	2026-10-17T08:00:00-05:00, or a date relative to today: today-7d, +90d, end-of-month, end-of-month+1m. Offsets are
+	in days (d), weeks (w), months (m) or years (y). From end-of-month, month and year offsets land on the last day of
+	the month (end-of-month+1m is the last day of next month), until a day or week offset is added. Invalid dates
+	print examples of the valid formats.
 2. [-d N ] Number of days in future after baseline date for new purge date.
-3. [-t ] test : whether or not to really change the purge dates, or just output the number of archives
+3. [-date date ] The new purge date itself, in any format -b takes, instead of -b plus -d. Can't be combined with -b, -d
+	or -policy.
+4. [-tz zone ] Time zone of the server, as an IANA name such as America/Chicago. Default is the time zone of this machine.
+	Both the baseline date and the archive expiration dates are read as calendar dates in this time zone.
+5. [-dest-id ids ] [-exclude-dest-id ids ] Only change archives in, or not in, these destinations. Comma-separated destination ids.
+6. [-dest-name names ] [-exclude-dest-name names ] The same, by destination name (not case-sensitive).
+7. [-org orgs ] Only change archives of devices in these orgs. Comma-separated org names or org ids.
+8. [-user users ] Only change archives of devices owned by these users. Comma-separated usernames.
+9. [-min-bytes N ] [-max-bytes N ] Only change archives whose size (archiveBytes) is at least / at most N bytes.
+	Filters can be combined: an archive is changed only if it matches every filter given. Within one list, any value
+	may match. The list flags may also be repeated, e.g. -org Sales -org Marketing.
+10. [-t ] test : whether or not to really change the purge dates, or just output the number of archives
 	that would be changed. Default is 'false'.
-4. [-a ] all : sets date for all archives in cold storage to the date, not just those that have a purge date
+11. [-a ] all : sets date for all archives in cold storage to the date, not just those that have a purge date
 	later than N days later than baseline). Default is 'false'
-5. [-s] Skip destinations that report having zero cold storage bytes. Default is 'false'.
-6. [-help] Show help.
+12. [-mode shorten|extend|set ] Which archives get the new purge date. shorten (the default): archives whose purge date is
+	later than the new date, so retention gets shorter. extend: archives whose purge date is earlier than the new date,
+	so retention gets longer; archives already held longer are not touched. set: all archives, the same as -a.
+13. [-policy file ] Take the new purge date and the mode from a JSON policy file of retention rules, instead of from
+	-d, -a and -mode. Each archive gets the purge date of the first rule that matches it (see Policy file below);
+	archives that match no rule are not changed. The filters above still apply. Can't be combined with -d, -a, -mode
+	or -date.
+14. [-forecast day|week|none ] In test mode (-t), forecast how many bytes the selected archives purge in each day or
+	week (weeks start on Sunday), per destination, with their current purge dates and with the new ones, to show the
+	storage saved before a change is approved. Purge dates already past count as today. Printed on the console and
+	written to a CSV file with the prefix "test_forecast_". Default is week; none turns the forecast off.
+15. [-s] Skip destinations that report having zero cold storage bytes. Default is 'false'.
+	Destinations whose coldBytes is null or can't be read are not skipped; the latter are logged as a warning.
+16. [-workers N] Number of purge date changes to make at the same time. Default is 4.
+17. [-rps N] Maximum number of API calls per second, across all workers. 0 means no limit. Default is 10.
+18. [-retries N] Number of times to retry an API call that fails with a dropped connection, a server error or a rate limit.
+	Default is 3. The number of attempts and any final failure are recorded in the log file.
+19. [-retrywait duration] Wait before the first retry, e.g. 2s or 500ms. The wait doubles after each retry. Default is 2s.
+20. [-retryput] Also retry the ColdStorage PUT calls that change purge dates. Default is 'false'.
+21. [-resume ] Continue a run that was interrupted while changing purge dates, e.g. killed or stopped by failures. The
+	archives it already changed are skipped and the rest are changed; results are added to the same CSV file. Use
+	the same arguments as the interrupted run (-workers, -rps, the retry options and the guardrails may differ). The
+	new purge dates must come out the same too, so a run with a relative date such as -b TODAY can only be resumed
+	on the day it started.
+22. [-plan file ] Select archives as usual, but instead of changing them write a plan file (JSON) listing each archive, its
+	current purge date and its new purge date, along with every command line argument of the run. Nothing is changed.
+	Only one of -plan, -apply, -rollback and -inventory may be given.
+23. [-apply file ] Make exactly the changes saved in a plan file, for example after a change-control approval. The current
+	purge date of each archive is read again first: archives whose date is no longer the one in the plan are skipped
+	and listed in the results file with the status "changed since plan" (or "not in cold storage"). The plan must have
+	been made against the same master server. -b, -d, -a and -s are ignored, and -t is refused: -apply always makes
+	the changes. Results file prefix: "apply_".
+24. [-rollback file ] Undo an earlier run: reads the results CSV file it wrote and puts back the Old Purge Date of each
+	archive listed with the status "changed", as the calendar date the server had stored, whatever -tz is. Writes
+	its own results file, with the prefix "rollback_". -b, -d and -a are ignored, and -t is refused: -rollback
+	always makes the changes. Results files of test runs (names starting with "test_") are refused. Rows whose old
+	purge date was null or malformed are refused and listed with the status "refused", unless -force is also given:
+	then a null old date is restored as null, and a malformed one from its leading YYYY-MM-DD if it has one. Results
+	files written before the Status column was added don't show whether they come from a test run, so they are
+	refused unless -force is given; then every row is rolled back.
+25. [-force ] With -rollback, also restore rows whose old purge date was null or malformed, and roll back results files
+	without a Status column. Default is 'false'.
+26. [-inventory ] Report what is in cold storage instead of changing purge dates: for each destination, the number
+	of archives, their total size in bytes (archiveBytes), how many expire in each month, and how many have a null or
+	malformed expiration date. Printed on the console and written to a CSV file with the prefix "inventory_". The
+	destination, org, user and size filters apply; -b, -d, -a, -mode and -t are ignored. Nothing is changed.
+27. [-min-days N ] Refuse to set a purge date earlier than N days from today. Checked before archives are read, and again
+	for every archive before anything is changed, including with -plan and -apply (against the date of the apply, not
+	of the plan). Not checked by -rollback, which puts back dates that were in place before. Default is 7.
+28. [-max-archives N ] Refuse to change more than N archives in one run (with -resume: the archives that remain).
+	Default is 0, no limit.
+29. [-yes ] Before any purge date is changed, the number of archives (and bytes) per destination is shown and the
+	change must be confirmed by typing yes. -yes skips the question, e.g. for scheduled runs. Default is 'false'.
+30. [-help] Show help.
 
 Example command:
 > ./setColdStoragePurgeDate -b 05-12-2016 -d 30 -t -s
@@ -17,6 +84,19 @@ Example command:
  The program would run in test mode only (and not actually change the archive expiration dates). Finally, the program would
  skips any destination in the environment that reports have zero bytes in cold storage.
 
+Policy file (-policy):
+	A JSON file with a list of rules. Each rule may match on destinationId, destinationName and org (lists; org takes
+	org names or ids; names are not case-sensitive); a rule without any of these matches every archive. "days" is the
+	number of days after the baseline date (-b) for the new purge date, and "mode" is shorten (the default), extend or
+	set, as with -mode. The first matching rule wins, so put the catch-all rule, if any, last. A field not listed here,
+	such as a misspelled "orgs", is an error. Example:
+		{ "rules": [
+			{ "name": "Legal", "org": ["Legal"], "days": 3650, "mode": "extend" },
+			{ "name": "Cloud", "destinationName": ["CrashPlan Central"], "days": 90 },
+			{ "name": "Everything else", "days": 180 }
+		] }
+	The results show how many archives each rule selected and how many matched no rule.
+
 Required file: hostinfo.conf
 		This file stores the master server to query, and the username/password combo to use for authentication.
 
@@ -28,23 +108,64 @@ Format of hostinfo.config:
 
 Output:
 	1. log file: one date-stamped log file per calendar day. Multiple runs on the same day append to this file.
-	2. Date-stamped CSV file with list of archives with changed purge date. Fields: Archive GUID, Old Purge Date, New Purge Date
+	2. Date-stamped CSV file with list of archives with changed purge date. Fields: Archive GUID, Old Purge Date, New Purge Date,
+		DestinationId, Status, Direction. Status is "changed", or "would change" in test mode. Archives that already had
+		the new purge date are listed too, with the status "already at target"; they are not changed. Direction is
+		"shorten" or "extend" depending on whether the new purge date is earlier or later than the old one, "set" if
+		the old date was null or malformed, and "none" if it is unchanged.
 		When run in test mode, the CSV file has the prefix "test_"
-	3. Console output similar to what is in the log file
+		The CSV file is written as archives are changed, so it is up to date even if the run is interrupted.
+	3. Checkpoint file setColdStoragePurgeDate.checkpoint, while purge dates are being changed. It is removed when the
+		run finishes without failures. If it is left behind, -resume continues the run.
+	4. Console output similar to what is in the log file. While purge dates are changed, the console shows a progress
+		line (archives done/total, failures, estimated time left) and the log file records each archive.
+	5. In test mode, unless -forecast none, a CSV file with the prefix "test_forecast_". Fields: DestinationId,
+		Period Start, Bytes Purged Before, Bytes Purged After, Difference. Period Start is the first day of the day or
+		week (YYYY-MM-DD), or "never" for archives with a null date. Only the archives that would change are counted.
+	6. With -inventory, a date-stamped CSV file with the prefix "inventory_" instead of the results file. Fields:
+		DestinationId, Destination Name, Expire Month, Archives, Bytes. Expire Month is YYYY-MM, "null or malformed"
+		for archives without a readable expiration date, or "total" for the row that sums up the destination.
 
 Misc. Notes:
-	When the baseline date is given as a date (format = MM-DD-YYYY), the time zone associated with the resultant date
-	object is UTC or GMT. When the date is specified with TODAY, the associated date object is the time zone of the
-	host machine. This produces an unintended effect: if you run the program more than once with the same baseline date
-	(not specified as TODAY), the program will keep rewriting the new purge date with PUT calls to ColdStorage API, because
-	it thinks the new purge date is GMT, whereas the purge date stored in the server is the time zone of the server.
-	Since users will not need to run more than once with a particular new archive expiration date, I don't think it's
-	worth spending time to fix at this time.
+	The baseline date and -date, in whatever format they are given, and the expiration dates returned by the server are all
+	converted to calendar dates in the time zone given with -tz (default: the time zone of this machine), and compared
+	as dates, not to the second. Set -tz to the time zone of the master server if it differs from this machine's.
+	Running the same command twice is then a no-op the second time: archives already set to the new date are not
+	selected again.
 
 Version 1.0
 Created 4-27-2016
 Author: Todd Ojala
 
+Modified 10-17-2026
+	API calls moved to the shared code42/client package. One pooled connection is reused for every request,
+	and errors are returned to main instead of quitting from inside the request functions.
+	HTTP status codes are checked. A bad password is reported as such, and the CSV file only lists archives whose
+	PUT call actually succeeded.
+	API calls that fail with a dropped connection or a server error are retried with backoff (-retries, -retrywait,
+	-retryput).
+	New -tz option. Dates are compared as calendar dates in the server's time zone, so reruns no longer rewrite
+	archives that already have the new purge date. Those archives are skipped, even with -a, counted separately and
+	listed in the CSV file with the status "already at target".
+	New -rollback mode, which puts back the old purge dates listed in the results CSV file of an earlier run.
+	New -plan and -apply modes, so that a selection can be reviewed and approved before it is applied.
+	The results CSV file is written as archives are changed, not at the end, and a checkpoint file lets -resume
+	continue an interrupted run.
+	New filters to choose archives by destination (-dest-id, -dest-name and their -exclude- forms), org (-org),
+	user (-user) and size (-min-bytes, -max-bytes).
+	New -mode option. -mode extend moves purge dates later, only for archives purged before the new date. The
+	results CSV file has a Direction column telling whether each change shortens or extends retention.
+	New -policy option: a JSON file of retention rules matched per archive, by destination and org.
+	Test mode (-t) also forecasts the bytes purged per day or week and destination, before and after the change (-forecast).
+	New -inventory mode: a read-only report of the archives in cold storage per destination and expiration month.
+	-b also takes YYYY-MM-DD, RFC 3339 and relative dates such as today-7d or end-of-month. New -date option.
+	Guardrails: -min-days (default 7), -max-archives, and a confirmation prompt before any change, skipped with -yes.
+	coldBytes is read exactly whether it is a string, a number or null. -s no longer skips destinations whose
+	coldBytes is null or can't be read; it warns about the latter.
+	Purge dates are changed by a pool of workers (-workers), capped at a number of API calls per second (-rps).
+	The console shows a progress line with the number of archives done and failed and an estimated time left;
+	the log file has a line for each archive.
+
 Modified 5-13-2016
 	Added help option.
 	Doesn't display cold bytes info for destinations of -s option selected
@@ -90,57 +211,61 @@ ARISING OUT OF OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"crypto/tls"
-	"encoding/csv"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
 )
 
 const (
-	API_DESTINATION         = "/api/Destination"
-	API_COLDSTORAGE         = "/api/ColdStorage"
-	shortFormDate           = "01-02-2006"
-	code42ArchiveTimeFormat = "2006-01-02T15:04:05.000-07:00"
+	shortFormDate = "01-02-2006"
 
-	helpText = "Command line parameters: \n [-b date] [-d days] [-t ] [-a ] [-s ] [-help]\n" +
+	helpText = "Command line parameters: \n [-b date] [-d days] [-date date] [-tz zone] [filters] [-t ] [-a ] [-mode shorten|extend|set] [-policy file] [-forecast day|week|none] [-s ] [-workers N] [-rps N] [-retries N] [-retrywait duration] [-retryput] [-resume] [-plan file] [-apply file] [-rollback file [-force]] [-inventory] [-min-days N] [-max-archives N] [-yes] [-help]\n" +
 		"\n Semantics:\n-b specifies the baseline date; -d specifies how many days later the purge date should be;\n" +
+		"  dates may be TODAY, MM-DD-YYYY, YYYY-MM-DD, RFC 3339 or relative: today-7d, +90d, end-of-month, end-of-month+1m;\n" +
+		"-date sets the new purge date directly, instead of -b plus -d;\n" +
+		"filters: -dest-id, -exclude-dest-id, -dest-name, -exclude-dest-name, -org, -user take comma-separated lists,\n" +
+		"  -min-bytes and -max-bytes take archive sizes in bytes; all given filters must match;\n" +
+		"-tz gives the time zone of the server as an IANA name, e.g. America/Chicago (default is the time zone of this machine);\n" +
 		"-t tells program to run in test  mode (default is false);\n-a tells program to change all archive expiration dates, not just " +
-		"archives that have an exp date greater than b+d (default is false);\n-s tells program to skip destinations that report have zero bytes in cold storage (default is false);\n" +
+		"archives that have an exp date greater than b+d (default is false);\n" +
+		"-mode shorten changes archives with an exp date after b+d (the default), -mode extend those with an exp date before b+d,\n" +
+		"  and -mode set all archives, like -a;\n" +
+		"-policy file takes the purge date and mode of each archive from the first matching rule of a JSON policy file (not with -d, -a, -mode or -date);\n" +
+		"-forecast, with -t, sums up the bytes purged per day or week and destination, with the old and the new purge dates (default week);\n" +
+		"-s tells program to skip destinations that report have zero bytes in cold storage (default is false);\n" +
+		"-workers sets how many purge date changes are made at the same time (default 4);\n" +
+		"-rps caps the number of API calls per second made by all workers together (default 10, 0 means no limit);\n" +
+		"-retries sets how many times an API call that fails with a dropped connection or server error is retried (default 3);\n" +
+		"-retrywait sets the wait before the first retry, e.g. 2s (default 2s). The wait doubles after each retry;\n" +
+		"-retryput also retries the PUT calls that change purge dates (default is false);\n" +
+		"-resume continues an interrupted run, skipping the archives it already processed. Use the same arguments as that run;\n" +
+		"-plan file saves the archives that would be changed to a plan file, without changing anything;\n" +
+		"-apply file makes the changes saved in a plan file, skipping archives whose purge date changed since the plan was made (not with -t);\n" +
+		"-rollback file puts back the Old Purge Date of each archive listed in a results CSV file of an earlier run (not with -t);\n" +
+		"-force, with -rollback, also restores archives whose old purge date was null or malformed, and accepts results files without a Status column;\n" +
+		"-min-days refuses new purge dates earlier than N days from today (default 7); -max-archives refuses runs that change more\n" +
+		"  than N archives (default 0, no limit); -yes skips the confirmation asked before purge dates are changed;\n" +
+		"-inventory reports the number and size of the archives in cold storage per destination and expiration month, changing nothing;\n" +
 		"-help displays this help message.\n"
 )
 
-type Records [][]string // The datatype that holds the results just before conversion to CSV
-
 var (
 	baseLineDate time.Time
 	daysLater    int
 	testOnly     bool
-	setAll       bool
 
-	url      string
-	username string
-	password string
-
-	destinations        []int
-	archivesToChange    []string
-	originalPurgeDates  []string
-	destinationsChanged []int
-
-	newPurgeDate time.Time
+	destinations    []client.Destination
+	changes         []purgeDateChange // Archives selected for a new purge date
+	alreadyAtTarget []purgeDateChange // Archives whose purge date is already the new date
 )
 
 func main() {
-	timeStamp := makeTimestamp() // For log file
+	timeStamp := client.DateStamp() // For log file
 
 	/* Open a log file. One log file created per day. Appends to day's log file if it already exists */
 	f, err := os.OpenFile("setColdStoragePurgeDateLog_"+timeStamp, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
@@ -154,17 +279,46 @@ func main() {
 	log.Println("Start at ", time.Now().String())
 
 	/* Define the command line flags and default options */
-	baseLineDateArg := flag.String("b", "TODAY", "Baseline date for calculating purge date: MM-DD-YYYY or TODAY")
+	baseLineDateArg := flag.String("b", "TODAY", "Baseline date for calculating purge date: TODAY, MM-DD-YYYY, YYYY-MM-DD, RFC 3339 or relative, e.g. today-7d")
+	purgeDateArg := flag.String("date", "", "New purge date, instead of -b plus -d. Same formats as -b.")
 	daysLaterArg := flag.Int("d", 0, "Number of days after baseline to set purge data to: integer")
 	testOnlyArg := flag.Bool("t", false, "Test only")
-	setAllArg := flag.Bool("a", false, "Set all archives in cold storage to the new date, instead of only archives with purge date > b+d.")
+	setAllArg := flag.Bool("a", false, "Set all archives in cold storage to the new date, instead of only archives with purge date > b+d. Same as -mode set.")
+	modeArg := flag.String("mode", "shorten", "Which archives to change: shorten (purge date after b+d), extend (purge date before b+d) or set (all).")
 	skipDestWithZeroCB := flag.Bool("s", false, "Skips destinations that have zero bytes in cold storage as reported by the API. Default is false.")
+	workersArg := flag.Int("workers", 4, "Number of purge date changes to make at the same time.")
+	rateArg := flag.Float64("rps", 10, "Maximum number of API calls per second, across all workers. 0 means no limit.")
+	retriesArg := flag.Int("retries", client.DefaultRetryPolicy.MaxAttempts-1, "Number of times to retry an API call that fails with a dropped connection or server error.")
+	retryWaitArg := flag.Duration("retrywait", client.DefaultRetryPolicy.BaseDelay, "Wait before the first retry. Doubled for each retry after that.")
+	retryPutArg := flag.Bool("retryput", false, "Also retry the ColdStorage PUT calls that change purge dates. Default is false.")
+	filter := archiveFilter{}
+	flag.Var(&filter.destIds, "dest-id", "Only change archives in these destinations, by id. Comma-separated.")
+	flag.Var(&filter.excludeDestIds, "exclude-dest-id", "Do not change archives in these destinations, by id. Comma-separated.")
+	flag.Var(&filter.destNames, "dest-name", "Only change archives in these destinations, by name. Comma-separated.")
+	flag.Var(&filter.excludeDestNames, "exclude-dest-name", "Do not change archives in these destinations, by name. Comma-separated.")
+	flag.Var(&filter.orgs, "org", "Only change archives of devices in these orgs, by name or id. Comma-separated.")
+	flag.Var(&filter.users, "user", "Only change archives of devices owned by these users, by username. Comma-separated.")
+	flag.Int64Var(&filter.minBytes, "min-bytes", 0, "Only change archives of at least this many bytes.")
+	flag.Int64Var(&filter.maxBytes, "max-bytes", 0, "Only change archives of at most this many bytes. 0 means no maximum.")
+	forecastArg := flag.String("forecast", forecastWeek, "With -t, forecast the bytes purged per day or week, before and after the change: day, week or none.")
+	policyArg := flag.String("policy", "", "JSON policy file with retention rules per destination or org. Replaces -d, -a and -mode.")
+	timeZoneArg := flag.String("tz", "Local", "Time zone of the server, as an IANA name such as America/Chicago. Default is the time zone of this machine.")
+	resumeArg := flag.Bool("resume", false, "Continue an interrupted run, skipping the archives it already processed. Use the same arguments as that run.")
+	planArg := flag.String("plan", "", "Write the archives that would be changed, with their current purge dates, to this plan file. Nothing is changed.")
+	applyArg := flag.String("apply", "", "Make the changes saved in this plan file, skipping archives whose purge date changed since the plan was made.")
+	rollbackArg := flag.String("rollback", "", "Results CSV file of an earlier run. Puts back the Old Purge Date of each archive it lists.")
+	guard := guardrails{}
+	flag.IntVar(&guard.minDays, "min-days", 7, "Refuse new purge dates earlier than this many days from today.")
+	flag.IntVar(&guard.maxArchives, "max-archives", 0, "Refuse to change more than this many archives in one run. 0 means no limit.")
+	flag.BoolVar(&guard.yes, "yes", false, "Change purge dates without asking for confirmation.")
+	inventoryArg := flag.Bool("inventory", false, "Report the archives in cold storage per destination and expiration month. Nothing is changed.")
+	forceArg := flag.Bool("force", false, "With -rollback, also restore archives whose old purge date was null or malformed, and accept results files without a Status column.")
 	showHelp := flag.Bool("help", false, "Show help.")
 
 	flag.Parse()
 
 	if *showHelp {
-		fmt.Println(helpText)
+		fmt.Print(helpText)
 		log.Println("Showing help and exiting.")
 		os.Exit(0)
 	}
@@ -172,21 +326,46 @@ func main() {
 	log.Println("Command line arguments:")
 	log.Println("-b:", *baseLineDateArg)
 	log.Println("-d:", *daysLaterArg)
+	log.Println("-date:", *purgeDateArg)
+	log.Println("-tz:", *timeZoneArg)
+	log.Println("-dest-id:", filter.destIds.String(), "-exclude-dest-id:", filter.excludeDestIds.String())
+	log.Println("-dest-name:", filter.destNames.String(), "-exclude-dest-name:", filter.excludeDestNames.String())
+	log.Println("-org:", filter.orgs.String(), "-user:", filter.users.String())
+	log.Println("-min-bytes:", filter.minBytes, "-max-bytes:", filter.maxBytes)
+	log.Println("-resume:", *resumeArg)
+	log.Println("-plan:", *planArg)
+	log.Println("-apply:", *applyArg)
+	log.Println("-rollback:", *rollbackArg)
+	log.Println("-force:", *forceArg)
+	log.Println("-inventory:", *inventoryArg)
+	log.Println("-min-days:", guard.minDays)
+	log.Println("-max-archives:", guard.maxArchives)
+	log.Println("-yes:", guard.yes)
 	log.Println("-t:", *testOnlyArg)
 	log.Println("-a:", *setAllArg)
+	log.Println("-mode:", *modeArg)
+	log.Println("-policy:", *policyArg)
+	log.Println("-forecast:", *forecastArg)
 	log.Println("-s", *skipDestWithZeroCB)
+	log.Println("-workers:", *workersArg)
+	log.Println("-rps:", *rateArg)
+	log.Println("-retries:", *retriesArg)
+	log.Println("-retrywait:", *retryWaitArg)
+	log.Println("-retryput:", *retryPutArg)
+
+	/* All dates are compared as calendar dates in the time zone of the server */
+	timeZone, err := time.LoadLocation(*timeZoneArg)
+	if err != nil {
+		fmt.Println("Time zone argument not valid:", err)
+		log.Fatalf("Time zone argument not valid: %v", err)
+	}
 
 	/*Convert baseline date parameter to a real date datatype/object */
-	if *baseLineDateArg == "TODAY" {
-		baseLineDate = time.Now()
-	} else {
-		baseLineDateTmp, err := time.Parse(shortFormDate, *baseLineDateArg)
-		if err != nil {
-			fmt.Println("Date argument not formatted correctly:", err)
-			log.Fatalf("Date argument not formatted correctly: %v", err)
-		} else {
-			baseLineDate = baseLineDateTmp
-		}
+	baseLineDate, err = parseDate(*baseLineDateArg, timeZone)
+	if err != nil {
+		fmt.Println("-b date argument not formatted correctly:", err)
+		fmt.Println(dateExamples)
+		log.Fatalf("Date argument not formatted correctly: %v", err)
 	}
 
 	/* Process the "days later" parameter */
@@ -194,219 +373,336 @@ func main() {
 		daysLater = *daysLaterArg
 	} else {
 		fmt.Println("The days later parameter is not valid. Must be greater than or equal to zero.")
-		log.Println("The days later parameter is not valid. Must be greater than or equal to zero. \n")
+		log.Println("The days later parameter is not valid. Must be greater than or equal to zero.")
 		log.Fatalln("Quitting.")
 	}
-	// fmt.Println("Days later paremeter =", daysLater)
 
-	/* Since we are here, calculate the new purge date */
-	daysLaterHours := time.Hour * 24 * time.Duration(daysLater)
-	newPurgeDate := baseLineDate.Add(daysLaterHours)
-	fmt.Println("New purge date=", newPurgeDate.Format(time.ANSIC))
-	log.Println("New purge date=", newPurgeDate.Format(time.ANSIC)) // Log the new purge date
+	/* Since we are here, calculate the new purge date, unless -date gives it */
+	newPurgeDate := baseLineDate.AddDate(0, 0, daysLater) // Calendar days, so a daylight saving change can't shift the date
+	if *purgeDateArg != "" {
+		if flagGiven("b") || flagGiven("d") {
+			fmt.Println("-date can't be combined with -b or -d.")
+			log.Fatalln("-date can't be combined with -b or -d.")
+		}
+		if newPurgeDate, err = parseDate(*purgeDateArg, timeZone); err != nil {
+			fmt.Println("-date argument not formatted correctly:", err)
+			fmt.Println(dateExamples)
+			log.Fatalf("-date argument not formatted correctly: %v", err)
+		}
+	}
+
+	/* -plan, -apply, -rollback and -inventory each replace the normal run, so only one of them may be given */
+	var runModes []string
+	for _, mode := range []struct {
+		name  string
+		given bool
+	}{{"-plan", *planArg != ""}, {"-apply", *applyArg != ""}, {"-rollback", *rollbackArg != ""}, {"-inventory", *inventoryArg}} {
+		if mode.given {
+			runModes = append(runModes, mode.name)
+		}
+	}
+	if len(runModes) > 1 {
+		fmt.Println(strings.Join(runModes, ", "), "can't be combined: give only one of -plan, -apply, -rollback and -inventory.")
+		log.Fatalln(strings.Join(runModes, ", "), "can't be combined.")
+	}
 
 	testOnly = *testOnlyArg
-	setAll = *setAllArg
+	if testOnly && *applyArg != "" {
+		fmt.Println("-t can't be combined with -apply: -apply always changes purge dates. Check the plan file instead.")
+		log.Fatalln("-t can't be combined with -apply.")
+	}
+	if testOnly && *rollbackArg != "" {
+		fmt.Println("-t can't be combined with -rollback: -rollback always changes purge dates.")
+		log.Fatalln("-t can't be combined with -rollback.")
+	}
+	if *forecastArg != forecastDay && *forecastArg != forecastWeek && *forecastArg != forecastNone {
+		fmt.Printf("-forecast %q is not valid: use day, week or none.\n", *forecastArg)
+		log.Fatalf("-forecast %q is not valid.", *forecastArg)
+	}
 
-	/* Read the host and user authentication info from the hostinfo.config file */
-	lines, err := readLines("hostinfo.config")
+	/* Fail early if the new purge date is too soon; the archives are checked again before they are changed */
+	if guard.minDays < 0 || guard.maxArchives < 0 {
+		fmt.Println("-min-days and -max-archives must be greater than or equal to zero.")
+		log.Fatalln("-min-days and -max-archives must be greater than or equal to zero.")
+	}
+	if err := guard.checkDate(newPurgeDate, timeZone); err != nil && !testOnly && *policyArg == "" && *applyArg == "" && *rollbackArg == "" && !*inventoryArg {
+		fmt.Println("Refusing to change purge dates:", err)
+		log.Fatalln("Refusing to change purge dates:", err)
+	}
+
+	/* A policy file replaces -d, -a and -mode with its rules */
+	var policy *retentionPolicy
+	if *policyArg != "" {
+		if *purgeDateArg != "" {
+			fmt.Println("-date can't be combined with -policy: the rules give the purge dates.")
+			log.Fatalln("-date can't be combined with -policy.")
+		}
+		if flagGiven("d") || flagGiven("a") || flagGiven("mode") {
+			fmt.Println("-d, -a and -mode can't be combined with -policy: the rules give the days and the mode.")
+			log.Fatalln("-d, -a and -mode can't be combined with -policy.")
+		}
+		if policy, err = readPolicy(*policyArg); err != nil {
+			fmt.Println("Can't read policy file:", err)
+			log.Fatalln("Can't read policy file:", err)
+		}
+	}
+
+	/* Which archives to change: -a is the same as -mode set */
+	mode, err := parseMode(*modeArg)
+	if err != nil {
+		fmt.Println(err)
+		log.Fatalln(err)
+	}
+	if *setAllArg {
+		if mode != modeShorten && mode != modeSet {
+			fmt.Println("-a can't be combined with -mode", mode)
+			log.Fatalln("-a can't be combined with -mode", mode)
+		}
+		mode = modeSet
+	}
 
+	/* Read the host and user authentication info from the hostinfo.config file */
+	c42, err := client.NewFromConfigFile("hostinfo.config")
 	if err != nil {
 		log.Fatalf("Can't read hostinfo.config file. Error: %s", err)
 	}
-	if len(lines) < 3 {
-		log.Fatalf("Info is missing from the config file. Check hostinfo.config file. Quitting. \n")
+	c42.Retry.MaxAttempts = *retriesArg + 1
+	c42.Retry.BaseDelay = *retryWaitArg
+	c42.Retry.RetryPUT = *retryPutArg
+	c42.Logf = log.Printf
+	c42.SetRateLimit(*rateArg)
+
+	log.Println("Connecting to host:", c42.BaseURL)
+
+	/* Apply mode: make the changes saved by an earlier -plan run, then quit */
+	if *applyArg != "" {
+		applyPlan(c42, *applyArg, *workersArg, guard)
+		return
 	}
 
-	url = strings.Trim(lines[0], " ") // Trimming extra spaces at beginning and end of lines
-	username = strings.Trim(lines[1], " ")
-	password = strings.Trim(lines[2], " ")
+	/* Inventory mode: report what is in cold storage, then quit */
+	if *inventoryArg {
+		inventory(c42, filter, timeZone)
+		return
+	}
 
-	// println(url, username, password )
-	log.Println("Connecting to host:", url)
+	/* Rollback mode: restore the old purge dates listed in a results file, then quit */
+	if *rollbackArg != "" {
+		rollback(c42, *rollbackArg, timeZone, *forceArg, *workersArg, guard)
+		return
+	}
+
+	if policy != nil {
+		fmt.Println("Purge dates from policy file", *policyArg, "in time zone", timeZone)
+		log.Println("Purge dates from policy file", *policyArg, "in time zone", timeZone)
+		policy.logRules(baseLineDate)
+	} else {
+		fmt.Println("New purge date=", newPurgeDate.Format(client.ArchiveDateFormat), timeZone)
+		log.Println("New purge date=", newPurgeDate.Format(client.ArchiveDateFormat), timeZone) // Log the new purge date
+	}
 
 	/* Get a list of all the archives in cold storage in this Code42 environment */
-	destinationRespMsg := struct {
-		Data struct {
-			Destinations []struct {
-				DestinationId   int         `json:"destinationId"`
-				Guid            string      `json:"guid"`
-				DestinationName string      `json:"destinationName"`
-				Type            string      `json:"type"`
-				ColdBytes       interface{} `json:"coldBytes"`
-			} `json:"destinations"`
-		} `json:"data"`
-	}{}
-
-	contents := makeRequest(url, API_DESTINATION)
-	/* Deserialize the JSON data into the right struct */
-	errJson := json.Unmarshal(contents, &destinationRespMsg)
-	if errJson != nil {
-		log.Println("Error unmarshalling JSON from Destination API:", errJson)
-		log.Fatalln("Quitting.")
+	allDestinations, err := c42.Destinations()
+	if err != nil {
+		quitOnAPIError("Error retrieving destinations from Destination API", err)
 	}
 
 	/* Filter out destinations that do not have cold storage bytes and place remaining in a list */
-	var coldBytesConverted int
-	for _, dest := range destinationRespMsg.Data.Destinations {
+	for _, dest := range allDestinations {
+		if !filter.matchDestination(dest) {
+			log.Printf("Destination %v (%v) left out by the destination filters.", dest.DestinationId, dest.DestinationName)
+			continue
+		}
 
 		if *skipDestWithZeroCB {
-			/* The data returned by the Destinations API, coldBytes field is of type String for Provider, but int for Cluster.
-			Until this is made consistent, need to deal with both types. 5-12-2016 */
-
-			switch dest.Type {
-			case "PROVIDER":
-				coldBytesString, _ := dest.ColdBytes.(string)
-				if coldBytesTmp, err := strconv.Atoi(coldBytesString); err != nil {
-					coldBytesConverted = 0
-				} else {
-					coldBytesConverted = coldBytesTmp
-				}
+			/* coldBytes is a string for PROVIDER destinations but a number for CLUSTER destinations; the client reads
+			both. Only a destination that really reports zero bytes is skipped: one whose coldBytes is null or can't be
+			read might still have archives. */
+			switch {
+			case dest.ColdBytes.Err != nil:
+				fmt.Printf("Warning: destination %v reports cold bytes that can't be read: %v. Not skipping it.\n", dest.DestinationId, dest.ColdBytes.Err)
+				log.Printf("Warning: destination %v (%v) reports cold bytes that can't be read: %v. Not skipping it.\n", dest.DestinationId, dest.Type, dest.ColdBytes.Err)
+			case dest.ColdBytes.Null:
+				fmt.Printf("Destination %v cold bytes= null. Not skipping it.\n", dest.DestinationId)
+				log.Printf("Destination %v cold bytes= null. Not skipping it.\n", dest.DestinationId)
 			default:
-				coldBytesTmp, _ := dest.ColdBytes.(float64) // You can only parse numbers of type float64 into interfaces from JSON
-				coldBytesConverted = int(coldBytesTmp)      // Now convert the float64 to an int
+				fmt.Printf("Destination %v cold bytes= %v \n", dest.DestinationId, dest.ColdBytes)
+				log.Printf("Destination %v cold bytes= %v \n", dest.DestinationId, dest.ColdBytes)
+				if dest.ColdBytes.Bytes <= 0 {
+					continue
+				}
 			}
-
-			fmt.Printf("Destination %v cold bytes= %v \n", dest.DestinationId, coldBytesConverted)
-			log.Printf("Destination %v cold bytes= %v \n", dest.DestinationId, coldBytesConverted)
-		} else {
-			coldBytesConverted = 1 // Not skipping any destinations, even if Cold Bytes is zero or null
 		}
 
-		if coldBytesConverted > 0 { // replaced 'coldBytesTmp > 0' with true since I took out the coldbytes check
-			destinations = append(destinations, dest.DestinationId)
-		}
+		destinations = append(destinations, dest)
 	}
 
-	// fmt.Println("The list of destinations:", destinations)
 	fmt.Printf("%d destinations have archives in cold storage.\n", len(destinations))
 	log.Printf("%d destinations have archives in cold storage.\n", len(destinations))
 
-	/* Define a struct to receive contents of ColdStorage API GET calls */
-	coldStorageRespMsg := struct {
-		Data struct {
-			ColdStorageRows []struct {
-				ArchiveGuid           string `json:"archiveGuid"`
-				ArchiveBytes          int    `json:"archiveBytes"` // Might use this to total purge bytes for log/reporting
-				ArchiveHoldExpireDate string `json:"archiveHoldExpireDate"`
-			} `json:"coldStorageRows"`
-		} `json:"data"`
-	}{}
-
 	/* Retrieve list of all cold storage archives that meet date criteria  */
-	endData := false                    // Flag for paging loop
 	nullArchiveHoldExpireDateCount := 0 // Keep track of the odd phenomomen of archives with null expire dates
-	for _, destId := range destinations {
-		endData = false // Reset flag to false again for each destination
+	filteredOutCount := 0               // Archives left out by -org, -user, -min-bytes or -max-bytes
+	unmatchedCount := 0                 // Archives no rule of the policy file matched
+	for _, dest := range destinations {
+		destId := dest.DestinationId
 		fmt.Println("Retrieving list of cold storage archives from destination Id:", destId)
 		log.Println("Retrieving list of cold storage archives from destination Id:", destId)
-		/* Need to page through the data. Can't get it all at once! */
-		for page := 1; endData != true; page++ {
-
-			resourcePlusQuery := API_COLDSTORAGE + "?destinationId=" + strconv.Itoa(destId) + "&pgNum=" + strconv.Itoa(page) //+ "&pgSize=2" // Change pagesize to 100+ in production
-			contents := makeRequest(url, resourcePlusQuery)
-
-			/* Deserialize the JSON data into a struct */
-			errJson := json.Unmarshal(contents, &coldStorageRespMsg)
-			if errJson != nil {
-				log.Println("Error unmarshalling JSON from coldStorage API GET call:", errJson)
-				log.Fatalln("Quitting.")
+		/* The client pages through the data. Can't get it all at once! */
+		coldStorageRows, err := c42.AllColdStorage(destId)
+		if err != nil {
+			quitOnAPIError("Error retrieving cold storage archives from ColdStorage API", err)
+		}
+		for _, coldStorageRow := range coldStorageRows {
+			if !filter.matchArchive(coldStorageRow) {
+				filteredOutCount++
+				continue
 			}
-			if len(coldStorageRespMsg.Data.ColdStorageRows) == 0 {
-				endData = true // No more data. Set flag to true.
-			} else {
-				for _, coldStorageRow := range coldStorageRespMsg.Data.ColdStorageRows {
-					// fmt.Println("Cold Storage found. GUID:", coldStorageRow.ArchiveGuid)
-
-					/* Does this archive meet the criteria? */
-					if setAll { // The -a flag was set. Change all archives' purge date
-						archivesToChange = append(archivesToChange, coldStorageRow.ArchiveGuid)
-						originalPurgeDates = append(originalPurgeDates, coldStorageRow.ArchiveHoldExpireDate)
-						destinationsChanged = append(destinationsChanged, destId)
-
-					} else {
-						// fmt.Println("Purge date:", coldStorageRow.ArchiveHoldExpireDate)
-						archivePurgeDateTmp, err := time.Parse(code42ArchiveTimeFormat, coldStorageRow.ArchiveHoldExpireDate)
-						if err != nil {
-							log.Printf("Date argument not formatted correctly for archive %v. Error: %v. Skipping.", coldStorageRow.ArchiveGuid, err)
-							nullArchiveHoldExpireDateCount++
-
-						} else {
-							/* Compare the archive's purge date with the new desired one. If it is bigger, add it to the list to change */
-
-							// fmt.Println("Diff between archive purge date and new purge date:", archivePurgeDateTmp.Sub(newPurgeDate))
-							if (archivePurgeDateTmp.Unix() - newPurgeDate.Unix()) > 0 { // In this case, the desired purge date is before the current
-								archivesToChange = append(archivesToChange, coldStorageRow.ArchiveGuid)
-								originalPurgeDates = append(originalPurgeDates, coldStorageRow.ArchiveHoldExpireDate)
-								destinationsChanged = append(destinationsChanged, destId)
-
-							}
-						}
-					}
+
+			/* With a policy file, the first matching rule gives the mode and the new purge date */
+			archiveMode, archivePurgeDate := mode, newPurgeDate
+			var rule *retentionRule
+			if policy != nil {
+				if rule = policy.match(dest, coldStorageRow); rule == nil {
+					unmatchedCount++
+					continue
 				}
+				archiveMode, archivePurgeDate = rule.mode, baseLineDate.AddDate(0, 0, rule.Days)
 			}
 
+			/* Does this archive meet the criteria? */
+			change := purgeDateChange{coldStorageRow.ArchiveGuid, coldStorageRow.ArchiveHoldExpireDate, archivePurgeDate, destId, int64(coldStorageRow.ArchiveBytes)}
+			archivePurgeDateTmp, err := parseArchiveDate(coldStorageRow.ArchiveHoldExpireDate, timeZone)
+			outcome := selectArchive(archiveMode, archivePurgeDateTmp, err, archivePurgeDate)
+			if rule != nil {
+				rule.count(outcome)
+			}
+			switch outcome {
+			case atTarget:
+				alreadyAtTarget = append(alreadyAtTarget, change)
+			case selected:
+				changes = append(changes, change)
+			case badDate:
+				log.Printf("Date argument not formatted correctly for archive %v. Error: %v. Skipping.", coldStorageRow.ArchiveGuid, err)
+				nullArchiveHoldExpireDateCount++
+			}
 		}
 
 	}
-	if !setAll {
+	if policy != nil {
+		policy.printSummary(unmatchedCount, testOnly)
+	}
+	if mode != modeSet || policy != nil {
 		fmt.Printf("%d archives had a null or malformed expiration date. See log for archive GUIDs.\n", nullArchiveHoldExpireDateCount)
 		log.Printf("%d archives had a null or malformed expiration date. See log for archive GUIDs.\n", nullArchiveHoldExpireDateCount)
 	}
-	// fmt.Println("list of archives to change:", archivesToChange)
+	if filteredOutCount > 0 {
+		fmt.Printf("%d archives were left out by the org, user and size filters.\n", filteredOutCount)
+		log.Printf("%d archives were left out by the org, user and size filters.\n", filteredOutCount)
+	}
+	fmt.Printf("%d archives already have the new purge date. Skipping them.\n", len(alreadyAtTarget))
+	log.Printf("%d archives already have the new purge date. Skipping them.\n", len(alreadyAtTarget))
+
+	/* Plan mode: save the selection for a later -apply instead of changing anything */
+	if *planArg != "" {
+		if err := guard.check(changes, timeZone, true); err != nil {
+			fmt.Println("Refusing to write the plan:", err)
+			log.Fatalln("Refusing to write the plan:", err)
+		}
+		writePlan(*planArg, c42.BaseURL, timeZone, changes)
+		return
+	}
+
+	/* Results are written to the CSV file as they happen */
+	resultsName := ""
+	started := time.Now()
+	targets := purgeTargets(newPurgeDate, baseLineDate, policy)
+	if *resumeArg {
+		/* Continue an interrupted run: skip the archives its results file lists as changed */
+		cp, err := readCheckpoint(targets)
+		if err != nil {
+			fmt.Println("Can't resume:", err)
+			log.Fatalln("Can't resume:", err)
+		}
+		processed, err := readResults(cp.ResultsFile)
+		if err != nil {
+			fmt.Println("Can't resume:", err)
+			log.Fatalln("Can't resume:", err)
+		}
+		done := map[string]bool{}
+		for _, row := range processed {
+			done[row.archiveGuid] = true
+		}
+		changes = withoutArchives(changes, done)
+		alreadyAtTarget = withoutArchives(alreadyAtTarget, done)
+		fmt.Printf("Resuming the run started %v. %d archives were already processed; %d remain.\n", cp.Started.Format(time.ANSIC), len(done), len(changes))
+		log.Printf("Resuming the run started %v, results file %v. %d archives were already processed; %d remain.\n", cp.Started.Format(time.ANSIC), cp.ResultsFile, len(done), len(changes))
+		resultsName = cp.ResultsFile
+		started = cp.Started
+	}
+
+	/* Last chance to stop before anything is changed */
+	if !testOnly {
+		guard.guard(changes, timeZone, true, true)
+	}
 
-	/* Create header for CSV output file */
-	changeResults := make(Records, 1)
-	headers := [4]string{"Archive GUID", "Old Purge Date", "New Purge Date", "DestinationId"}
-	for _, columnName := range headers {
-		changeResults[0] = append(changeResults[0], columnName)
+	var changeResults *resultsFile
+	if resultsName != "" {
+		changeResults = appendResults(resultsName)
+	} else {
+		prefix := "" // Prefix will say test_ if it was only a test run
+		if testOnly {
+			prefix = "test_"
+		}
+		changeResults = createResults(prefix)
 	}
+	defer changeResults.close()
 
-	csvFilePrefix := "" // Prefix will say test_ if it was only a test run
+	for _, change := range alreadyAtTarget {
+		changeResults.write(change.record(statusAtTarget))
+	}
 
-	totalCount := 0 // Keep track of total number of cold storage purge date changes made
+	totalCount := 0  // Keep track of total number of cold storage purge date changes made
+	failedCount := 0 // Archives whose PUT was refused by the server or never answered
 	if !testOnly {
+		if !*resumeArg {
+			if _, err := os.Stat(checkpointFile); err == nil {
+				fmt.Println("Note: a checkpoint from an interrupted run was found. It is replaced by this run; use -resume to continue an interrupted run instead.")
+				log.Println("Replacing the checkpoint of an interrupted run.")
+			}
+		}
+		writeCheckpoint(changeResults, started, targets)
+
 		fmt.Println("Starting to change achive expiration dates.")
 		log.Println("Starting to change achive expiration dates.")
 		/* Use the Cold Storage API with PUT to change the purge date */
-		for i, archiveGuid := range archivesToChange {
-			fmt.Print(".")
-			success := changePurgeDate(archiveGuid, newPurgeDate)
-			if success == false {
-				log.Println("Could not change purge date for archive with GUID=", archiveGuid)
-			} else {
-				totalCount++
-				data := []string{archivesToChange[i], originalPurgeDates[i], newPurgeDate.Format(code42ArchiveTimeFormat), strconv.Itoa(destinationsChanged[i])}
-				changeResults = append(changeResults, data)
-			}
+		var err error
+		totalCount, failedCount, err = applyChanges(c42, changes, *workersArg, changeResults)
+		if err == nil && failedCount == 0 {
+			removeCheckpoint()
+		} else {
+			fmt.Println("Run -resume with the same arguments to retry the archives that were not changed.")
 		}
 
 	} else {
-		for i, _ := range archivesToChange {
-			data := []string{archivesToChange[i], originalPurgeDates[i], newPurgeDate.Format(code42ArchiveTimeFormat), strconv.Itoa(destinationsChanged[i])}
-			changeResults = append(changeResults, data)
+		for _, change := range changes {
+			changeResults.write(change.record(statusWouldChange))
+		}
+		fmt.Printf("This was only a test. %d archives in cold storage would have had their purge dates changed.\n", len(changes))
+		log.Printf("This was only a test. %d archives in cold storage would have had their purge dates changed.\n", len(changes))
+		if *forecastArg != forecastNone {
+			writeForecast(changes, *forecastArg, timeZone)
 		}
-		fmt.Printf("This was only a test. %d archives in cold storage would have had their purge dates changed.\n", len(archivesToChange))
-		log.Printf("This was only a test. %d archives in cold storage would have had their purge dates changed.\n", len(archivesToChange))
-		csvFilePrefix = "test_"
 	}
-	fmt.Print("\n") // Separate dot progress indicator from next message
 	fmt.Println("Total number of purge dates changed:", totalCount)
 	log.Println("Total number of purge dates changed:", totalCount)
-
-	/* Write CSV file and exit */
-	csvfile, csv_err := os.Create(csvFilePrefix + "results_" + strings.Replace(time.Now().Format(time.Stamp), " ", "_", -1) + ".csv")
-	if csv_err != nil {
-		log.Fatalln("Error creating CSV file:", csv_err)
-	}
-
-	defer csvfile.Close()
-	w := csv.NewWriter(csvfile)
-	w.WriteAll(changeResults) // calls Flush internally
-
-	if write_err := w.Error(); write_err != nil {
-		log.Fatalln("Error writing csv:", write_err)
+	fmt.Println("Total number of archives already at the new purge date:", len(alreadyAtTarget))
+	log.Println("Total number of archives already at the new purge date:", len(alreadyAtTarget))
+	if failedCount > 0 {
+		fmt.Printf("%d purge date changes failed. See log for archive GUIDs and errors.\n", failedCount)
+		log.Printf("%d purge date changes failed.\n", failedCount)
 	}
+	fmt.Println("Results written to", changeResults.name)
 
 	fmt.Println("Done.")
 	log.Println("Done.")
@@ -415,91 +711,33 @@ func main() {
 
 /* Functions used in this program are defined below */
 
-/* This function returns a string timestamp for loggin */
-func makeTimestamp() string {
-	timenow := time.Now().String()
-	return timenow[0:10]
-}
-
-func readLines(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	return lines, scanner.Err()
+/* flagGiven reports whether the flag name was given on the command line */
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
 }
 
-func makeRequest(url, resource string) []byte {
-	/* This function performs a GET request on the desired resource and returns a response */
-
-	/* Build the transport method for the request. Skip ssl errors */
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-
-	client := &http.Client{Transport: tr} // Create an http client
-
-	/* Build a request to the DeviceBackupReport resource */
-	fullPath := url + resource
-
-	req, err := http.NewRequest("GET", fullPath, nil)
-	req.SetBasicAuth(username, password)
-	resp, err := client.Do(req) // Perform the GET request
-	if err != nil {
-		log.Fatalln("Error making request in makeRequest function. Error:", err)
-	}
-
-	/* Get the results */
-	defer resp.Body.Close()
-	contents, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalf("Error reading response in makeRequest function: %s \n", err)
+/* withoutArchives returns the changes whose archive is not in skip */
+func withoutArchives(changes []purgeDateChange, skip map[string]bool) []purgeDateChange {
+	var remaining []purgeDateChange
+	for _, change := range changes {
+		if !skip[change.ArchiveGuid] {
+			remaining = append(remaining, change)
+		}
 	}
-
-	return contents
-
+	return remaining
 }
 
-func changePurgeDate(guid string, date time.Time) bool {
-	/* Changes the archiveHoldExpireDate (aka purge date) of archives in cold storage. This function depends
-	on some global variables and constants: url, API_COLDSTORAGE. Ideally, these would be passed as parameters. (future change?) */
-
-	/* Convert the new purge date time into a string of the right format for the ColdStorage API */
-	stringDate := date.Format("2006-01-02")
-
-	var jsonStr = []byte(`{ "archiveHoldExpireDate" : "` + stringDate + `" }`)
-
-	/* Build the transport method for the request. Skip ssl errors */
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+/* quitOnAPIError prints and logs an error returned by the Code42 API, with a hint for the usual causes, and quits */
+func quitOnAPIError(context string, err error) {
+	msg := fmt.Sprintf("%s: %v", context, err)
+	if hint := client.ErrorHint(err); hint != "" {
+		msg += "\n" + hint
 	}
-
-	client := &http.Client{Transport: tr} // Create an http client
-	/* Build a request to the DeviceBackupReport resource */
-	fullPath := url + API_COLDSTORAGE + "/" + guid + "?idType=guid"
-	req, err := http.NewRequest("PUT", fullPath, bytes.NewBuffer(jsonStr))
-	req.SetBasicAuth(username, password)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error making request while changing purge date for archive with GUID %v: %s \n", guid, err) // Continue with other archives though
-		return false
-	}
-	defer resp.Body.Close()
-
-	/* Below is for debugging only */
-	// fmt.Println("response Status:", resp.Status)
-	// fmt.Println("response Headers:", resp.Header)
-	// body, _ := ioutil.ReadAll(resp.Body)
-	// fmt.Println("response Body:", string(body))
-	return true
-
+	fmt.Println(msg)
+	log.Println(msg)
+	log.Fatalln("Quitting.")
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ojalatodd/golang/code42/client"
+)
+
+/* Values of the Status column of the results CSV file */
+const (
+	statusChanged     = "changed"
+	statusWouldChange = "would change" // Test mode
+	statusAtTarget    = "already at target"
+	statusRefused     = "refused" // Rollback of a row whose old purge date was null or malformed, without -force
+
+	statusChangedSincePlan = "changed since plan" // -apply: purge date is no longer the planned old date
+	statusNotInColdStorage = "not in cold storage"
+)
+
+/* purgeDateChange is one archive selected to get a new purge date */
+type purgeDateChange struct {
+	ArchiveGuid   string
+	OldPurgeDate  string    // archiveHoldExpireDate as returned by the ColdStorage API
+	NewPurgeDate  time.Time // Zero means null
+	DestinationId int
+	ArchiveBytes  int64 // For the -t forecast; not known to -apply and -rollback
+}
+
+/* record returns the row written to the results CSV file for this change */
+func (c purgeDateChange) record(status string) []string {
+	return []string{c.ArchiveGuid, c.OldPurgeDate, c.newPurgeDateString(), strconv.Itoa(c.DestinationId), status, c.direction()}
+}
+
+/* newPurgeDateString returns the new purge date in the format the API returns dates in, or "" for null */
+func (c purgeDateChange) newPurgeDateString() string {
+	if c.NewPurgeDate.IsZero() {
+		return ""
+	}
+	return c.NewPurgeDate.Format(client.ArchiveTimeFormat)
+}
+
+// changePurgeDates sends the ColdStorage PUT for every change, workers at a time. done is called once for each change
+// that was attempted, with the error of its PUT, if any. Calls to done never overlap, so it may write files and counters
+// without locking.
+//
+// If the server refuses the credentials, the remaining changes are abandoned and the error is returned.
+func changePurgeDates(c42 *client.Client, changes []purgeDateChange, workers int, done func(change purgeDateChange, err error)) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan purgeDateChange)
+	stop := make(chan struct{})
+	var mu sync.Mutex // Serializes calls to done
+	var authErr error
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for change := range jobs {
+				var err error
+				if change.NewPurgeDate.IsZero() {
+					err = c42.ClearColdStoragePurgeDate(change.ArchiveGuid) // Only a rollback to a null date does this
+				} else {
+					err = c42.SetColdStoragePurgeDate(change.ArchiveGuid, change.NewPurgeDate)
+				}
+
+				mu.Lock()
+				done(change, err)
+				if errors.Is(err, client.ErrAuth) && authErr == nil {
+					/* Every remaining PUT would be refused too */
+					authErr = err
+					close(stop)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, change := range changes {
+		select {
+		case jobs <- change:
+		case <-stop:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return authErr
+}
+
+// applyChanges changes the purge dates of changes with a progress line on the console and a log line for each
+// archive. Each archive changed is written to results as soon as its PUT succeeds. It returns the number of
+// archives changed and failed, and the error that stopped the run early, if any.
+func applyChanges(c42 *client.Client, changes []purgeDateChange, workers int, results *resultsFile) (int, int, error) {
+	totalCount := 0
+	failedCount := 0
+	bar := newProgress(len(changes))
+	err := changePurgeDates(c42, changes, workers, func(change purgeDateChange, err error) {
+		if err != nil {
+			log.Printf("Could not change purge date for archive with GUID=%v: %v", change.ArchiveGuid, err)
+			failedCount++
+		} else {
+			log.Printf("Changed purge date for archive with GUID=%v from %q to %q", change.ArchiveGuid, change.OldPurgeDate, change.newPurgeDateString())
+			results.write(change.record(statusChanged))
+			totalCount++
+		}
+		bar.add(err != nil)
+	})
+	bar.finish()
+	if err != nil {
+		fmt.Println("The server refused the change:", err)
+		log.Println("Authentication failed while changing purge dates. Stopping.")
+	}
+	return totalCount, failedCount, err
+}
+
+/* progress prints a single, regularly rewritten status line on the console while purge dates are changed */
+type progress struct {
+	total   int
+	done    int
+	failed  int
+	start   time.Time
+	printed time.Time
+}
+
+func newProgress(total int) *progress {
+	return &progress{total: total, start: time.Now()}
+}
+
+/* add counts one more finished change and refreshes the console line, at most a few times per second */
+func (p *progress) add(failed bool) {
+	p.done++
+	if failed {
+		p.failed++
+	}
+	if p.done == p.total || time.Since(p.printed) >= 250*time.Millisecond {
+		p.print()
+	}
+}
+
+func (p *progress) print() {
+	p.printed = time.Now()
+	eta := "unknown"
+	if p.done > 0 {
+		elapsed := time.Since(p.start)
+		remaining := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+		eta = remaining.Round(time.Second).String()
+	}
+	fmt.Printf("\r%d/%d archives done, %d failed, ETA %s   ", p.done, p.total, p.failed, eta)
+}
+
+/* finish ends the console line so that the next message starts on its own line */
+func (p *progress) finish() {
+	p.print()
+	fmt.Print("\n")
+}